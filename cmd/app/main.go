@@ -19,7 +19,25 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// bring back any tables that were running when the process last stopped
+	if config.AppConfig.SNAPSHOT_DIR != "" {
+		store, err := engine.NewFileSnapshotStore(config.AppConfig.SNAPSHOT_DIR)
+		if err != nil {
+			log.Fatalf("Failed to open snapshot store: %v", err)
+		}
+		engine.UseSnapshotStore(store)
+		if err := engine.RestoreEngines(); err != nil {
+			log.Fatalf("Failed to restore engines: %v", err)
+		}
+	}
+
 	http.HandleFunc("/start-engine", engine.StartEngineHandler)
+	http.HandleFunc("/admin/engines", engine.RequireAdminSecret(engine.AdminListEnginesHandler))
+	http.HandleFunc("/admin/engine", engine.RequireAdminSecret(engine.AdminEngineHandler))
+	http.HandleFunc("/admin/pause", engine.RequireAdminSecret(engine.AdminPauseHandler))
+	http.HandleFunc("/admin/resume", engine.RequireAdminSecret(engine.AdminResumeHandler))
+	http.HandleFunc("/admin/blinds", engine.RequireAdminSecret(engine.AdminBlindsHandler))
+	http.HandleFunc("/admin/stop", engine.RequireAdminSecret(engine.AdminStopHandler))
 	if err := http.ListenAndServe(":8080", nil); err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
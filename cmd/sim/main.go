@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/wegman7/game-engine/config"
+	"github.com/wegman7/game-engine/internal/engine"
+)
+
+// sim plays bots against each other without a server and reports how the engine held up
+func main() {
+	env := flag.String("env", "prod", "Environment to load the config for: dev or prod")
+	hands := flag.Int("hands", 10000, "Number of hands to play")
+	bots := flag.String("bots", "tag,callingStation,random,tag,callingStation,random", "Comma separated bot for each seat")
+	game := flag.String("game", "holdem", "Game to play")
+	structure := flag.String("structure", "", "Betting structure, the game's default if empty")
+	smallBlind := flag.Float64("sb", 1, "Small blind")
+	bigBlind := flag.Float64("bb", 2, "Big blind")
+	chips := flag.Float64("chips", 0, "Buy in for each bot, 100 big blinds if 0")
+	runItTwice := flag.Bool("runItTwice", false, "Let all in players run it more than once")
+	verbose := flag.Bool("v", false, "Log everything the engine does")
+	flag.Parse()
+
+	if err := config.Load(*env); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	logger := log.New(log.Writer(), "", log.Flags())
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	report, err := engine.Simulate(engine.Simulation{
+		Table: engine.StartGameRequest{
+			RoomName:   "sim",
+			SmallBlind: *smallBlind,
+			BigBlind:   *bigBlind,
+			Game:       *game,
+			Structure:  *structure,
+			RunItTwice: *runItTwice,
+		},
+		Bots:  strings.Split(*bots, ","),
+		Chips: *chips,
+		Hands: *hands,
+	})
+	if err != nil {
+		logger.Fatalf("Failed to start simulation: %v", err)
+	}
+
+	fmt.Printf("%d hands in %v, %.0f hands per second\n", report.Hands, report.Elapsed, report.HandsPerSecond)
+	users := make([]string, 0, len(report.Net))
+	for user := range report.Net {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return report.Net[users[i]] > report.Net[users[j]]
+	})
+	for _, user := range users {
+		fmt.Printf("%-20s %+12.2f\n", user, report.Net[user])
+	}
+	fmt.Printf("%d crashes\n", len(report.Crashes))
+	for _, crash := range report.Crashes {
+		fmt.Println(crash)
+	}
+}
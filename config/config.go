@@ -16,6 +16,13 @@ type Config struct {
     PAUSE_LONG time.Duration
     MAX_PLAYERS int
 	BACKEND_URL string
+	SNAPSHOT_DIR string
+	ADMIN_SECRET string
+	RATHOLE_WINDOW time.Duration
+	MAX_RUNS int
+	RUN_IT_TIMEOUT time.Duration
+	CHOOSE_GAME_TIMEOUT time.Duration
+	DISCARD_TIMEOUT time.Duration
 }
 
 var AppConfig Config
@@ -35,6 +42,13 @@ func Load(env string) error {
 			PAUSE_LONG: 3 * time.Millisecond,
 			MAX_PLAYERS: 9,
 			BACKEND_URL: os.Getenv("BACKEND_URL"),
+			SNAPSHOT_DIR: "snapshots",
+			ADMIN_SECRET: os.Getenv("ADMIN_SECRET"),
+			RATHOLE_WINDOW: 2 * time.Minute,
+			MAX_RUNS: 3,
+			RUN_IT_TIMEOUT: 5 * time.Second,
+			CHOOSE_GAME_TIMEOUT: 15 * time.Second,
+			DISCARD_TIMEOUT: 10 * time.Second,
 		}
 	case "prod":
 		// prod env vars will be loaded into docker container at runtime
@@ -46,6 +60,13 @@ func Load(env string) error {
 			PAUSE_LONG: 2000 * time.Millisecond,
 			MAX_PLAYERS: 9,
 			BACKEND_URL: os.Getenv("BACKEND_URL"),
+			SNAPSHOT_DIR: os.Getenv("SNAPSHOT_DIR"),
+			ADMIN_SECRET: os.Getenv("ADMIN_SECRET"),
+			RATHOLE_WINDOW: 60 * time.Minute,
+			MAX_RUNS: 3,
+			RUN_IT_TIMEOUT: 10 * time.Second,
+			CHOOSE_GAME_TIMEOUT: 30 * time.Second,
+			DISCARD_TIMEOUT: 15 * time.Second,
 		}
 	default:
 		return fmt.Errorf("unknown environment: %s", env)
@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/wegman7/game-engine/config"
+)
+
+const adminSecretHeader = "X-Admin-Secret"
+
+type AdminRoomRequest struct {
+	RoomName   string  `json:"roomName"`
+	SmallBlind float64 `json:"smallBlind"`
+	BigBlind   float64 `json:"bigBlind"`
+}
+
+type AdminRoomSummary struct {
+	RoomName    string `json:"roomName"`
+	Connected   bool   `json:"connected"`
+	EngineState string `json:"engineState"`
+	Players     int    `json:"players"`
+	Paused      bool   `json:"paused"`
+}
+
+type AdminRoomResponse struct {
+	RoomName    string         `json:"roomName"`
+	EngineState string         `json:"engineState"`
+	Paused      bool           `json:"paused"`
+	State       SerializeState `json:"state"`
+}
+
+// RequireAdminSecret rejects any request that doesn't carry the shared admin secret
+func RequireAdminSecret(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := config.AppConfig.ADMIN_SECRET
+		given := r.Header.Get(adminSecretHeader)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(given)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func AdminListEnginesHandler(w http.ResponseWriter, r *http.Request) {
+	rooms := make([]AdminRoomSummary, 0)
+	for roomName, e := range listEngines() {
+		summary := AdminRoomSummary{RoomName: roomName}
+		if e != nil {
+			e.mu.Lock()
+			summary.Connected = true
+			summary.EngineState = e.engineState.String()
+			summary.Players = len(e.state.players)
+			summary.Paused = e.state.paused
+			e.mu.Unlock()
+		}
+		rooms = append(rooms, summary)
+	}
+	writeJSON(w, rooms)
+}
+
+func AdminEngineHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := findEngine(w, r.URL.Query().Get("roomName"))
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	response := AdminRoomResponse{
+		RoomName:    e.roomName,
+		EngineState: e.engineState.String(),
+		Paused:      e.state.paused,
+		State:       e.serializeState(),
+	}
+	e.mu.Unlock()
+	writeJSON(w, response)
+}
+
+func AdminPauseHandler(w http.ResponseWriter, r *http.Request) {
+	setPaused(w, r, true)
+}
+
+func AdminResumeHandler(w http.ResponseWriter, r *http.Request) {
+	setPaused(w, r, false)
+}
+
+func setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	req, ok := decodeAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	e, ok := findEngine(w, req.RoomName)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	e.state.paused = paused
+	e.mu.Unlock()
+	log.Println("Admin set paused =", paused, "for room", req.RoomName)
+	writeJSON(w, StartGameResponse{Message: fmt.Sprintf("Room %s paused: %t", req.RoomName, paused)})
+}
+
+// AdminBlindsHandler changes the blinds, the new level starts with the next hand
+func AdminBlindsHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	e, ok := findEngine(w, req.RoomName)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	err := e.state.setBlinds(req.SmallBlind, req.BigBlind)
+	e.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, StartGameResponse{Message: fmt.Sprintf("Blinds for room %s will be %v/%v next hand", req.RoomName, req.SmallBlind, req.BigBlind)})
+}
+
+// AdminStopHandler stops a room immediately, chips in the current hand go back to the players
+func AdminStopHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	e, ok := findEngine(w, req.RoomName)
+	if !ok {
+		return
+	}
+
+	log.Println("Admin force stopping room", req.RoomName)
+	e.forceStop()
+	writeJSON(w, StartGameResponse{Message: fmt.Sprintf("Stopped engine for room %s", req.RoomName)})
+}
+
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request) (AdminRoomRequest, bool) {
+	req := AdminRoomRequest{}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+func findEngine(w http.ResponseWriter, roomName string) (*engine, bool) {
+	e := getEngine(roomName)
+	if e == nil {
+		http.Error(w, "No running engine for room", http.StatusNotFound)
+		return nil, false
+	}
+	return e, true
+}
+
+func writeJSON(w http.ResponseWriter, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
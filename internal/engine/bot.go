@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/chehsunliu/poker"
+	"github.com/wegman7/game-engine/config"
+)
+
+// a bot sits in a seat like any other player, whenever it has something it can do the engine
+// shows it the table from its seat and queues the event it answers with
+type bot interface {
+	act(view PlayerView, legal LegalActions) Event
+}
+
+var bots = map[string]func() bot{
+	"random":         func() bot { return randomBot{} },
+	"callingStation": func() bot { return callingStationBot{} },
+	"tag":            func() bot { return tagBot{} },
+}
+
+func newBot(name string) (bot, error) {
+	create, ok := bots[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown bot: %s", name)
+	}
+	return create(), nil
+}
+
+// runBots asks every bot with a legal action what it wants to do, a bot with a command already
+// queued waits for it to be processed
+func (e *engine) runBots() {
+	for _, p := range e.state.players {
+		if p.bot == nil || e.hasQueuedCommand(p.user) {
+			continue
+		}
+		legal := e.state.legalActions(p, e.engineState)
+		if !legal.any() {
+			continue
+		}
+		event := p.bot.act(createPlayerView(e.state, p), legal)
+		event.User = p.user
+		e.gameCommands = append(e.gameCommands, event)
+	}
+}
+
+func (e *engine) hasQueuedCommand(user string) bool {
+	for _, command := range e.gameCommands {
+		if command.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+// randomBot picks any legal action, bets anywhere from the minimum to the maximum
+type randomBot struct{}
+
+func (randomBot) act(view PlayerView, legal LegalActions) Event {
+	switch {
+	case legal.RunIt:
+		return Event{EngineCommand: "runIt", Runs: 1 + rand.Intn(max(config.AppConfig.MAX_RUNS, 1))}
+	case legal.Discard:
+		count := legal.MinDiscards + rand.Intn(legal.MaxDiscards-legal.MinDiscards+1)
+		return discardEvent(pickCards(view.HoleCards, count))
+	}
+
+	actions := make([]string, 0, 4)
+	for action, ok := range map[string]bool{"fold": legal.Fold, "check": legal.Check, "call": legal.Call, "bet": legal.Bet} {
+		if ok {
+			actions = append(actions, action)
+		}
+	}
+	sort.Strings(actions)
+	action := actions[rand.Intn(len(actions))]
+	if action == "bet" {
+		return Event{EngineCommand: "bet", Chips: betSize(legal.MinBet+rand.Float64()*(legal.MaxBet-legal.MinBet), legal)}
+	}
+	return Event{EngineCommand: action}
+}
+
+// callingStationBot never folds and never raises, it stands pat and runs it once
+type callingStationBot struct{}
+
+func (callingStationBot) act(view PlayerView, legal LegalActions) Event {
+	return passiveAction(view, legal)
+}
+
+func passiveAction(view PlayerView, legal LegalActions) Event {
+	switch {
+	case legal.RunIt:
+		return Event{EngineCommand: "runIt", Runs: 1}
+	case legal.Discard:
+		return discardEvent(lowestCards(view.HoleCards, legal.MinDiscards))
+	case legal.Check:
+		return Event{EngineCommand: "check"}
+	}
+	return Event{EngineCommand: "call"}
+}
+
+// tagBot plays tight and aggressive, preflop it only plays hands from its chart and raises the
+// best of them, after the flop it bets two pair or better, calls with a pair and gives up without
+// one, in games it doesn't know it plays like a calling station
+type tagBot struct{}
+
+func (tagBot) act(view PlayerView, legal LegalActions) Event {
+	if legal.RunIt || legal.Discard || len(view.HoleCards) != 2 || len(view.UpCards) > 0 {
+		return passiveAction(view, legal)
+	}
+
+	board := []poker.Card{}
+	if len(view.Boards) > 0 {
+		board = view.Boards[0]
+	}
+	strength := preflopStrength(view.HoleCards)
+	if len(board) >= 3 {
+		strength = postflopStrength(view.HoleCards, board)
+	}
+
+	switch {
+	case strength == strongHand && legal.Bet:
+		// bet three quarters of the pot, within what the structure allows
+		return Event{EngineCommand: "bet", Chips: betSize(view.CurrentBet+view.Pot*0.75, legal)}
+	case strength >= playableHand && legal.Check:
+		return Event{EngineCommand: "check"}
+	case strength >= playableHand && legal.Call:
+		return Event{EngineCommand: "call"}
+	case legal.Check:
+		return Event{EngineCommand: "check"}
+	}
+	return Event{EngineCommand: "fold"}
+}
+
+const (
+	weakHand = iota
+	playableHand
+	strongHand
+)
+
+// preflopStrength is a simple hand chart: big pairs and big aces are strong, other pairs, suited
+// aces, two broadway cards and suited connectors are playable
+func preflopStrength(holeCards []poker.Card) int {
+	high, low := holeCards[0].Rank(), holeCards[1].Rank()
+	if low > high {
+		high, low = low, high
+	}
+	suited := holeCards[0].Suit() == holeCards[1].Suit()
+	const ten, queen, king, ace = 8, 10, 11, 12
+
+	switch {
+	case high == low && high >= ten:
+		return strongHand
+	case high == ace && (low == king || (low == queen && suited)):
+		return strongHand
+	case high == low:
+		return playableHand
+	case high == ace && suited:
+		return playableHand
+	case low >= ten:
+		return playableHand
+	case suited && high-low == 1 && low >= 3:
+		return playableHand
+	}
+	return weakHand
+}
+
+func postflopStrength(holeCards []poker.Card, board []poker.Card) int {
+	switch class := poker.RankClass(evaluateHoldem(holeCards, board)); {
+	case class <= 7:
+		return strongHand
+	case class == 8:
+		return playableHand
+	}
+	return weakHand
+}
+
+// betSize rounds a bet to the cent, within what the structure allows
+func betSize(chips float64, legal LegalActions) float64 {
+	return min(max(math.Round(chips*100)/100, legal.MinBet), legal.MaxBet)
+}
+
+func discardEvent(cards []poker.Card) Event {
+	names := make([]string, len(cards))
+	for i, card := range cards {
+		names[i] = card.String()
+	}
+	return Event{EngineCommand: "discard", Cards: names}
+}
+
+func pickCards(cards []poker.Card, count int) []poker.Card {
+	picked := append([]poker.Card{}, cards...)
+	rand.Shuffle(len(picked), func(i, j int) {
+		picked[i], picked[j] = picked[j], picked[i]
+	})
+	return picked[:count]
+}
+
+func lowestCards(cards []poker.Card, count int) []poker.Card {
+	sorted := append([]poker.Card{}, cards...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Rank() < sorted[j].Rank()
+	})
+	return sorted[:count]
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wegman7/game-engine/config"
+)
+
+func TestLegalActions(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Structure: potLimit})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands; i++ {
+		e.tick()
+	}
+	legal := s.legalActions(s.spotlight, e.engineState)
+	if !legal.Fold || legal.Check || !legal.Call || !legal.Bet {
+		t.Errorf("Expected the small blind to fold, call or raise, got %+v", legal)
+	}
+	if legal.MinBet != 4 || legal.MaxBet != 6 {
+		t.Errorf("Expected a pot limit raise from 4 to 6, got %v to %v", legal.MinBet, legal.MaxBet)
+	}
+	if other := s.legalActions(s.spotlight.nextInHand, e.engineState); other.any() {
+		t.Errorf("Expected nothing to do out of turn, got %+v", other)
+	}
+}
+
+func TestBotsPlay(t *testing.T) {
+	config.AppConfig.MAX_PLAYERS = 9
+	e := createEngine(nil, StartGameRequest{SmallBlind: 1, BigBlind: 2})
+	for _, join := range []Event{
+		{EngineCommand: "join", SeatId: 1, User: "random", Chips: 200, Bot: "random"},
+		{EngineCommand: "join", SeatId: 4, User: "callingStation", Chips: 200, Bot: "callingStation"},
+		{EngineCommand: "join", SeatId: 7, User: "tag", Chips: 200, Bot: "tag"},
+	} {
+		if err := e.handleSitCommand(join); err != nil {
+			t.Fatalf("Expected %v to join, got %v", join.Bot, err)
+		}
+	}
+	if err := e.handleSitCommand(Event{EngineCommand: "join", SeatId: 8, User: "nobody", Chips: 200, Bot: "nobody"}); err == nil {
+		t.Errorf("Expected an unknown bot to be rejected")
+	}
+
+	e.startNextHand()
+	hands := 0
+	for i := 0; i < 5000 && hands < 20; i++ {
+		if e.engineState == StateEndHand {
+			hands++
+		}
+		e.tick()
+		if err := e.state.checkInvariants(e.engineState); err != nil {
+			t.Fatal(err)
+		}
+		e.runBots()
+		if e.engineState == StateProcessSitCommands {
+			break
+		}
+	}
+	if hands == 0 {
+		t.Fatalf("Expected the bots to play some hands, got stuck in %v", e.engineState)
+	}
+	if total := e.state.totalChips(); math.Abs(total-600) > 0.001 {
+		t.Errorf("Expected 600 chips at the table, got %v", total)
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+import "time"
+
+// clock is where the table gets the time from, pauses and timeouts are deadlines the engine
+// checks every tick instead of sleeping, tests and the simulator move a fake clock themselves
+type clock interface {
+	now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) now() time.Time {
+	return time.Now()
+}
+
+// fakeClock only moves when it's told to
+type fakeClock struct {
+	time time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.time
+}
+
+func (c *fakeClock) advance(duration time.Duration) {
+	c.time = c.time.Add(duration)
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/wegman7/game-engine/config"
+)
+
+// dealer's choice tables play one orbit at a time, before each orbit the chooser picks the game
+// from the table's list, the choice starts with the player on the button and moves one seat left
+// every orbit, if nobody picks in time the table's default game is played
+
+func (s *state) isGameChoiceDue() bool {
+	if len(s.dealersChoice) == 0 {
+		return false
+	}
+	return s.chooser == "" || s.gameHands >= s.orbitSize
+}
+
+func (s *state) startGameChoice() error {
+	if s.dealer == nil {
+		return errors.New("dealer is nil")
+	}
+	// the first chooser is whoever gets the button next hand, after that it's the next seat
+	seat := s.dealer.seatId
+	if s.chooser != "" {
+		seat = s.chooserSeat
+	}
+	chooser := s.nextSittingInAfter(seat)
+	if chooser == nil {
+		return errors.New("not enough players in hand")
+	}
+	s.chooser = chooser.user
+	s.chooserSeat = chooser.seatId
+	s.gameChosen = false
+	s.chooseDeadline = s.clock.now().Add(config.AppConfig.CHOOSE_GAME_TIMEOUT)
+	return nil
+}
+
+// nextSittingInAfter finds the first player sitting in to the left of a seat, the seat may be
+// empty since the last chooser could have left
+func (s *state) nextSittingInAfter(seat int) *player {
+	var next, lowest *player
+	for _, p := range s.players {
+		if p.sittingOut {
+			continue
+		}
+		if p.seatId > seat && (next == nil || p.seatId < next.seatId) {
+			next = p
+		}
+		if lowest == nil || p.seatId < lowest.seatId {
+			lowest = p
+		}
+	}
+	// past the last seat it wraps around to the lowest
+	if next == nil {
+		return lowest
+	}
+	return next
+}
+
+func (s *state) chooseGame(user string, game RotationGame) error {
+	if user != s.chooser {
+		return errors.New("only the player choosing this orbit can pick the game")
+	}
+	if s.gameChosen {
+		return errors.New("the game has already been chosen")
+	}
+	allowed := false
+	for _, choice := range s.dealersChoice {
+		if choice.Game == game.Game && (game.Structure == "" || choice.Structure == "" || choice.Structure == game.Structure) {
+			allowed = true
+			if game.Structure == "" {
+				game.Structure = choice.Structure
+			}
+		}
+	}
+	if !allowed {
+		return errors.New("game is not allowed at this table")
+	}
+	s.setGame(game.Game, game.Structure)
+	s.gameChosen = true
+	return nil
+}
+
+// resolveGameChoice plays the default game if the chooser ran out of time
+func (s *state) resolveGameChoice() {
+	if !s.gameChosen {
+		s.setGame(s.defaultGame.Game, s.defaultGame.Structure)
+	}
+	s.gameHands = 0
+	s.prevState = nil
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/chehsunliu/poker"
+)
+
+const cardRanks = "23456789TJQKA"
+const cardSuits = "shdc"
+
+// deck replaces poker.Deck so variants can play with fewer cards and discards can be reshuffled
+type deck struct {
+	cards []poker.Card
+}
+
+func newDeck(cards []poker.Card) *deck {
+	d := &deck{cards: append([]poker.Card{}, cards...)}
+	rand.Shuffle(len(d.cards), func(i, j int) {
+		d.cards[i], d.cards[j] = d.cards[j], d.cards[i]
+	})
+	return d
+}
+
+func (d *deck) draw(n int) []poker.Card {
+	cards := make([]poker.Card, n)
+	copy(cards, d.cards[:n])
+	d.cards = d.cards[n:]
+	return cards
+}
+
+func (d *deck) remaining() int {
+	return len(d.cards)
+}
+
+// cardsFrom builds one of every card ranked lowestRank or higher
+func cardsFrom(lowestRank string) []poker.Card {
+	cards := make([]poker.Card, 0, 52)
+	for _, rank := range cardRanks[strings.Index(cardRanks, lowestRank):] {
+		for _, suit := range cardSuits {
+			cards = append(cards, poker.NewCard(string(rank)+string(suit)))
+		}
+	}
+	return cards
+}
+
+func newStandardDeck() *deck {
+	return newDeck(cardsFrom("2"))
+}
+
+// short deck takes out the deuces through fives
+func newShortDeck() *deck {
+	return newDeck(cardsFrom("6"))
+}
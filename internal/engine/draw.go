@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chehsunliu/poker"
+)
+
+// draw games have no board, between betting rounds everyone still in the hand, all in or not,
+// discards in turn starting left of the button and is dealt replacements from the stub
+
+func (s *state) startDraw() {
+	s.spotlight = s.psuedoDealer.nextInHand
+	s.lastAggressor = s.spotlight
+}
+
+// replaceCards swaps the discards for new cards, if the stub runs out the earlier discards are
+// shuffled into a new stub, the player's own discards are only added back once they've drawn
+func (s *state) replaceCards(p *player, discards []poker.Card) {
+	kept := make([]poker.Card, 0, len(p.holeCards))
+	for _, card := range p.holeCards {
+		if !containsCard(discards, card) {
+			kept = append(kept, card)
+		}
+	}
+
+	drawn := s.deck.draw(min(len(discards), s.deck.remaining()))
+	if len(drawn) < len(discards) {
+		s.deck = newDeck(s.discards)
+		s.discards = nil
+		drawn = append(drawn, s.deck.draw(len(discards)-len(drawn))...)
+	}
+	p.holeCards = append(kept, drawn...)
+	s.discards = append(s.discards, discards...)
+}
+
+// parseCards reads cards like "As" or "Td" sent by a client
+func parseCards(names []string) ([]poker.Card, error) {
+	cards := make([]poker.Card, 0, len(names))
+	for _, name := range names {
+		if len(name) != 2 || !strings.ContainsRune(cardRanks, rune(name[0])) || !strings.ContainsRune(cardSuits, rune(name[1])) {
+			return nil, fmt.Errorf("invalid card: %s", name)
+		}
+		cards = append(cards, poker.NewCard(name))
+	}
+	return cards, nil
+}
+
+func (p *player) verifyLegalDiscard(discards []poker.Card) error {
+	for i, card := range discards {
+		if !containsCard(p.holeCards, card) {
+			return errors.New("can only discard cards in your hand")
+		}
+		if containsCard(discards[:i], card) {
+			return errors.New("can't discard the same card twice")
+		}
+	}
+	return nil
+}
+
+func containsCard(cards []poker.Card, card poker.Card) bool {
+	for _, c := range cards {
+		if c == card {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,9 @@ package engine
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/chehsunliu/poker"
@@ -30,46 +32,152 @@ const (
 	StateEndHand
 	StateDealStreet
 	StatePauseAfterEndHand
+	StatePaused
+	StateRunItVote
+	StateDraw
+	StateChooseGame
+	StateDiscard
 )
 
+var engineStateNames = map[engineState]string{
+	StateProcessSitCommands:             "processSitCommands",
+	StateStartHand:                      "startHand",
+	StatePauseAfterStartHand:            "pauseAfterStartHand",
+	StatePostBlinds:                     "postBlinds",
+	StatePauseAfterPostBlinds:           "pauseAfterPostBlinds",
+	StateDealCards:                      "dealCards",
+	StateProcessGameCommands:            "processGameCommands",
+	StatePauseAfterEveryoneFolded:       "pauseAfterEveryoneFolded",
+	StateEveryoneFoldedPayout:           "everyoneFoldedPayout",
+	StatePauseAfterEveryoneFoldedPayout: "pauseAfterEveryoneFoldedPayout",
+	StateEndStreet:                      "endStreet",
+	StatePauseAfterEndStreet:            "pauseAfterEndStreet",
+	StateShowdown:                       "showdown",
+	StatePauseAfterShowdown:             "pauseAfterShowdown",
+	StateEndHand:                        "endHand",
+	StateDealStreet:                     "dealStreet",
+	StatePauseAfterEndHand:              "pauseAfterEndHand",
+	StatePaused:                         "paused",
+	StateRunItVote:                      "runItVote",
+	StateDraw:                           "draw",
+	StateChooseGame:                     "chooseGame",
+	StateDiscard:                        "discard",
+}
+
+func (es engineState) String() string {
+	return engineStateNames[es]
+}
+
 type engine struct {
+	// mu guards everything below, the engine loop, the websocket reader and the admin api all touch it
+	mu           sync.Mutex
 	conn         *websocket.Conn
 	gameCommands []Event
 	sitCommands  []Event
 	state        *state
 	roomName     string
 	engineState  engineState
+	stopEngine   chan struct{}
+	stopOnce     sync.Once
+	// enteredState is when the engine moved to the state it's in, pauses are timed from it
+	enteredState time.Time
 }
 
-func createEngine(conn *websocket.Conn, roomName string, smallBlind float64, bigBlind float64) *engine {
+func createEngine(conn *websocket.Conn, req StartGameRequest) *engine {
 	return &engine{
 		conn:         conn,
 		gameCommands: make([]Event, 0),
 		sitCommands:  make([]Event, 0),
-		state:        createState(smallBlind, bigBlind, 60),
-		roomName:     roomName,
+		state:        createTableState(req),
+		roomName:     req.RoomName,
 		engineState:  StateProcessSitCommands,
+		stopEngine:   make(chan struct{}),
 	}
 }
 
-func (e *engine) run(stopEngine chan struct{}) {
+// createTableState sets up the state for a new table with the options it was started with
+func createTableState(req StartGameRequest) *state {
+	s := createState(req.SmallBlind, req.BigBlind, 60)
+	s.owner = req.Owner
+	s.private = req.Private
+	s.minBuyIn = req.MinBuyIn
+	s.maxBuyIn = req.MaxBuyIn
+	s.runItTwice = req.RunItTwice
+	s.bombPotEvery = req.BombPotEvery
+	s.bombPotAnte = req.BombPotAnte
+	s.ante = req.Ante
+	s.rotation = req.Rotation
+	s.rotateEvery = req.RotateEvery
+	s.rotateOrbits = req.RotateOrbits
+	s.dealersChoice = req.DealersChoice
+	s.defaultGame = RotationGame{Game: req.Game, Structure: req.Structure}
+	// the start handler rejects unknown games, a snapshot from an older build falls back to the default
+	if len(s.rotation) > 0 {
+		s.setGame(s.rotation[0].Game, s.rotation[0].Structure)
+	} else {
+		s.setGame(req.Game, req.Structure)
+	}
+	return s
+}
+
+func restoreEngine(conn *websocket.Conn, snapshot TableSnapshot) (*engine, error) {
+	s, err := restoreState(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	e := createEngine(conn, snapshot.startGameRequest())
+	e.state = s
+	// snapshots are only taken at the end of a hand, so the table was playing and picks up where it left off
+	e.queueEvent(Event{EngineCommand: "startGame", User: snapshot.Owner})
+	return e, nil
+}
+
+func (e *engine) run() {
 	e.transitionState(StateProcessSitCommands)
 	for {
 		select {
-		case <-stopEngine:
-			delete(runningEngines, e.roomName)
+		case <-e.stopEngine:
+			releaseEngine(e.roomName)
 			log.Println("Stopping engine for room", e.roomName)
 			return
 		default:
 			time.Sleep(config.AppConfig.ENGINE_LOOP_PAUSE)
+			e.mu.Lock()
+			// a stop that came in while we waited for the lock closed the table, there's nothing left to tick
+			if e.isStopped() {
+				e.mu.Unlock()
+				continue
+			}
 			e.tick()
-			e.state.checkInvariants(e.engineState)
+			if err := e.state.checkInvariants(e.engineState); err != nil {
+				log.Fatalf("INVARIANT: %v", err)
+			}
+			e.runBots()
 			e.sendState()
+			e.mu.Unlock()
 		}
 	}
 }
 
+func (e *engine) stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopEngine)
+	})
+}
+
+func (e *engine) isStopped() bool {
+	select {
+	case <-e.stopEngine:
+		return true
+	default:
+		return false
+	}
+}
+
 func (e *engine) tick() {
+	if e.state.street != BetweenHands {
+		e.processTopUps()
+	}
 	// use states here
 	switch e.engineState {
 	case StateProcessSitCommands:
@@ -106,86 +214,272 @@ func (e *engine) tick() {
 		e.endHand()
 	case StatePauseAfterEndHand:
 		e.pauseAfterEndHand()
+	case StatePaused:
+		e.whilePaused()
+	case StateRunItVote:
+		e.runItVote()
+	case StateDraw:
+		e.processGameCommand()
+	case StateChooseGame:
+		e.whileChoosingGame()
+	case StateDiscard:
+		e.whileDiscarding()
 	}
 }
 
 func (e *engine) transitionState(newEngineState engineState) {
 	log.Println("Transitioning state from", e.engineState, "to", newEngineState)
-	log.Println("Street:", e.state.street, "Community cards:", e.state.communityCards)
+	log.Println("Street:", e.state.street, "Boards:", e.state.boards)
 	e.engineState = newEngineState
+	e.enteredState = e.state.clock.now()
 }
 
 func (e *engine) queueEvent(event Event) {
-	if event.EngineCommand == "fold" || event.EngineCommand == "check" || event.EngineCommand == "call" || event.EngineCommand == "bet" {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if event.EngineCommand == "fold" || event.EngineCommand == "check" || event.EngineCommand == "call" || event.EngineCommand == "bet" || event.EngineCommand == "runIt" || event.EngineCommand == "discard" || event.EngineCommand == "show" || event.EngineCommand == "preAction" {
 		e.gameCommands = append(e.gameCommands, event)
 	} else {
 		e.sitCommands = append(e.sitCommands, event)
 	}
 }
 
+// commands only the room owner may send, any user can send them if the room has no owner
+var ownerCommands = map[string]bool{
+	"startGame":   true,
+	"pauseGame":   true,
+	"resumeGame":  true,
+	"kick":        true,
+	"approveJoin": true,
+	"denyJoin":    true,
+	"setBuyIn":    true,
+	"setBlinds":   true,
+}
+
 func (e *engine) processSitCommand() {
 	// copy e.commands so it doesn't change while we're iterating
 	commandsCopy := e.sitCommands
 	e.sitCommands = make([]Event, 0)
 	for _, command := range commandsCopy {
 		log.Println("processing sit command: ", command)
-		user := command.User
+		if err := e.handleSitCommand(command); err != nil {
+			log.Println("Error processing sit command: ", err)
+			e.sendError(command.User, err)
+		}
+	}
+}
 
-		if command.EngineCommand == "join" {
-			seatId, err := determineSeatId(command, e.state.players)
-			if err != nil {
-				log.Println("Error determining seat id: ", err)
-				continue
-			}
-			command.SeatId = seatId
-			p := createPlayer(command)
-			err2 := e.state.addPlayer(p)
-			if err2 != nil {
-				log.Println("Error adding player: ", err2)
-				e.state.prevState = nil
-				continue
-			}
-		} else if command.EngineCommand == "leave" {
-			e.state.removePlayer(e.state.players[user])
-		} else if command.EngineCommand == "startGame" {
-			e.transitionState(StateStartHand)
-		} else {
-			e.state.players[user].makeAction(&command, e, e.state)
+// processTopUps takes chips added during a hand right away, they're held until the hand ends and
+// every other sit command still waits for it
+func (e *engine) processTopUps() {
+	remaining := make([]Event, 0, len(e.sitCommands))
+	for _, command := range e.sitCommands {
+		if command.EngineCommand != "addChips" {
+			remaining = append(remaining, command)
+			continue
+		}
+		log.Println("processing top-up: ", command)
+		if err := e.handleSitCommand(command); err != nil {
+			log.Println("Error processing top-up: ", err)
+			e.sendError(command.User, err)
+		}
+	}
+	e.sitCommands = remaining
+}
+
+func (e *engine) handleSitCommand(command Event) error {
+	if ownerCommands[command.EngineCommand] {
+		if err := e.state.verifyOwner(command.User); err != nil {
+			return err
 		}
 	}
+
+	switch command.EngineCommand {
+	case "join":
+		return e.requestJoin(command)
+	case "leave":
+		p, err := e.state.findPlayer(command.User)
+		if err != nil {
+			return err
+		}
+		e.state.removePlayer(p)
+	case "startGame":
+		e.startNextHand()
+	case "pauseGame", "resumeGame":
+		e.processPauseCommand(command)
+	case "kick":
+		return e.kick(command.Target)
+	case "approveJoin":
+		return e.approveJoin(command.Target)
+	case "denyJoin":
+		return e.denyJoin(command.Target)
+	case "setBuyIn":
+		return e.state.setBuyIn(command.MinBuyIn, command.MaxBuyIn)
+	case "setBlinds":
+		return e.state.setBlinds(command.SmallBlind, command.BigBlind)
+	case "bombPotVote":
+		return e.state.voteBombPot(command.User)
+	case "chooseGame":
+		if e.engineState != StateChooseGame {
+			return errors.New("no game to choose right now")
+		}
+		return e.state.chooseGame(command.User, RotationGame{Game: command.Game, Structure: command.Structure})
+	default:
+		p, err := e.state.findPlayer(command.User)
+		if err != nil {
+			return err
+		}
+		return p.makeAction(&command, e, e.state)
+	}
+	return nil
+}
+
+// on a private table everyone but the owner waits for the owner to approve their seat
+func (e *engine) requestJoin(command Event) error {
+	if e.state.private && command.User != e.state.owner {
+		if _, exists := e.state.players[command.User]; exists {
+			return errors.New("player already at the table")
+		}
+		e.state.pendingJoins[command.User] = command
+		e.state.prevState = nil
+		return nil
+	}
+	return e.join(command)
+}
+
+func (e *engine) join(command Event) error {
+	if err := e.state.verifyBuyIn(command.User, command.Chips); err != nil {
+		return err
+	}
+	seatId, err := determineSeatId(command, e.state.players)
+	if err != nil {
+		return err
+	}
+	command.SeatId = seatId
+	p := createPlayer(command)
+	if command.Bot != "" {
+		if p.bot, err = newBot(command.Bot); err != nil {
+			return err
+		}
+		p.botName = command.Bot
+	}
+	if err := e.state.addPlayer(p); err != nil {
+		e.state.prevState = nil
+		return err
+	}
+	return nil
+}
+
+func (e *engine) approveJoin(user string) error {
+	command, ok := e.state.pendingJoins[user]
+	if !ok {
+		return errors.New("no join request from player")
+	}
+	delete(e.state.pendingJoins, user)
+	e.state.prevState = nil
+	if err := e.join(command); err != nil {
+		e.sendError(user, err)
+		return err
+	}
+	return nil
+}
+
+func (e *engine) denyJoin(user string) error {
+	if _, ok := e.state.pendingJoins[user]; !ok {
+		return errors.New("no join request from player")
+	}
+	delete(e.state.pendingJoins, user)
+	e.state.prevState = nil
+	e.sendError(user, errors.New("join request denied"))
+	return nil
+}
+
+// kick is a sit command so it's only processed between hands, the player finishes the current hand
+func (e *engine) kick(user string) error {
+	p, err := e.state.findPlayer(user)
+	if err != nil {
+		return err
+	}
+	log.Println("Kicking player", user, "from room", e.roomName)
+	e.state.removePlayer(p)
+	return nil
 }
 
 func (e *engine) processGameCommand() {
 	// copy e.commands so it doesn't change while we're iterating
 	commandsCopy := e.gameCommands
 	e.gameCommands = make([]Event, 0)
+	engineState := e.engineState
+	// a pre action goes as soon as the spotlight gets to its player, even before anything queued
+	e.applyPreActions()
 	for _, command := range commandsCopy {
 		log.Println("processing game command: ", command)
-		user := command.User
-		err := e.state.players[user].makeAction(&command, e, e.state)
+		p, err := e.state.findPlayer(command.User)
+		if err == nil && e.engineState != engineState {
+			// once a command ends the street the rest were sent for a street that's over
+			err = errors.New("the action has moved on")
+		}
+		if err == nil && (e.engineState == StateDraw || e.engineState == StateDiscard) && command.EngineCommand != "discard" && command.EngineCommand != "preAction" {
+			// no betting while players draw or discard, it picks up again once everyone has
+			err = errors.New("waiting for players to discard")
+		}
+		if err == nil && isBettingOver(e.engineState) && command.EngineCommand != "show" {
+			err = errors.New("the betting is over")
+		}
+		if err == nil {
+			err = p.makeAction(&command, e, e.state)
+		}
 		if err != nil {
 			log.Println("Error processing game command: ", err)
+			e.sendError(command.User, err)
+			continue
 		}
+		e.applyPreActions()
 	}
 }
 
 func (e *engine) startHand() {
+	// the last hand's history stays up until the next one starts
+	e.state.history = nil
+	e.state.applyPendingBlinds()
 	e.state.chipsInHandTotal = e.state.totalChips()
 	if err := e.state.performDealerRotation(); err != nil {
 		log.Println("Error rotating dealer: ", err)
 		e.transitionState(StateProcessSitCommands)
 		return
 	}
-	e.state.street = Preflop
+	e.state.street = e.state.variant.streets[0]
+	e.state.boards = make([][]poker.Card, e.state.variant.boards)
+	e.state.handCount++
+	e.state.startGameHand()
+	e.state.bombPot = e.state.isBombPotDue()
+	if e.state.bombPot {
+		log.Println("Bomb pot in room", e.roomName)
+		e.state.bombPotVotes = make(map[string]bool)
+	}
 	e.transitionState(StatePauseAfterStartHand)
 }
 
 func (e *engine) pauseAfterStartHand() {
-	time.Sleep(config.AppConfig.PAUSE_MEDIUM)
+	if e.waiting(config.AppConfig.PAUSE_MEDIUM) {
+		return
+	}
 	e.transitionState(StatePostBlinds)
 }
 
 func (e *engine) postBlinds() {
+	if e.state.bombPot {
+		e.state.postBombPotAntes()
+		e.transitionState(StatePauseAfterPostBlinds)
+		return
+	}
+	// stud has no blinds, the bring in is posted once the upcards are out
+	if e.state.variant.stud {
+		e.state.postAntes()
+		e.transitionState(StatePauseAfterPostBlinds)
+		return
+	}
+
 	playerCount := e.state.countPlayersInHand()
 	var sb *player
 	var bb *player
@@ -200,95 +494,199 @@ func (e *engine) postBlinds() {
 		e.state.spotlight = bb.nextInHand
 		e.state.lastAggressor = bb.nextInHand
 	}
-	sb.putChipsInPot(e.state, e.state.smallBlind)
-	bb.putChipsInPot(e.state, e.state.bigBlind)
+	if e.state.variant.buttonAnte {
+		e.state.postButtonAnte()
+	}
+	sb.putChipsInPot(e.state, min(e.state.smallBlind, sb.chips))
+	e.state.record(sb.user, "smallBlind", sb.chipsInPot)
+	bb.putChipsInPot(e.state, min(e.state.bigBlind, bb.chips))
+	e.state.record(bb.user, "bigBlind", bb.chipsInPot)
 
 	e.state.minRaise = e.state.bigBlind
 	e.state.currentBet = e.state.bigBlind
+	// the big blind is the first bet of the street
+	e.state.bets = 1
 
 	e.transitionState(StatePauseAfterPostBlinds)
 }
 
 func (e *engine) pauseAfterPostBlinds() {
-	time.Sleep(config.AppConfig.PAUSE_MEDIUM)
+	if e.waiting(config.AppConfig.PAUSE_MEDIUM) {
+		return
+	}
 	e.transitionState(StateDealCards)
 }
 
 func (e *engine) dealCards() {
-	e.state.deck = poker.NewDeck()
+	// a deck that's already there was stacked for a scripted hand
+	if e.state.deck == nil {
+		e.state.deck = e.state.variant.newDeck()
+	}
+	if e.state.variant.stud {
+		e.state.dealStudCards()
+		e.state.postBringIn()
+		if e.state.spotlight == nil {
+			e.transitionState(StateEndStreet)
+		} else {
+			e.transitionState(StateProcessGameCommands)
+		}
+		return
+	}
 	pointer := e.state.dealer.nextInHand
 	for {
-		pointer.holeCards = e.state.deck.Draw(2)
+		pointer.holeCards = e.state.deck.draw(e.state.variant.holeCards)
 		if pointer == e.state.dealer {
 			break
 		}
 		pointer = pointer.nextInHand
 	}
-	e.transitionState(StateProcessGameCommands)
+
+	// bomb pots skip preflop betting, ending the street right away deals the flop
+	if e.state.bombPot || e.state.skipAllInBlinds() {
+		e.transitionState(StateEndStreet)
+	} else {
+		e.transitionState(StateProcessGameCommands)
+	}
 }
 
 func (e *engine) pauseAfterEveryoneFolded() {
-	time.Sleep(config.AppConfig.PAUSE_MEDIUM)
+	e.processGameCommand()
+	if e.waiting(config.AppConfig.PAUSE_MEDIUM) {
+		return
+	}
 	e.transitionState(StateEveryoneFoldedPayout)
 }
 
 func (e *engine) everyoneFoldedPayout() {
-	winner := e.state.psuedoDealer
+	e.state.returnUncalledBet()
+	e.state.buildPots()
 	e.state.collectPot()
-	winner.chips += e.state.pot
-	e.state.collectedPot = 0
-	e.state.pot = 0
+	// the last player left takes every pot they're in, anything they couldn't have won goes to
+	// whoever folded out of it last
+	for _, pot := range e.state.pots {
+		e.state.payPot(pot.eligible, pot.amount)
+	}
+	e.state.pots = nil
 	e.transitionState(StatePauseAfterEveryoneFoldedPayout)
 }
 
 func (e *engine) pauseAfterEveryoneFoldedPayout() {
-	time.Sleep(config.AppConfig.PAUSE_MEDIUM)
+	e.processGameCommand()
+	if e.waiting(config.AppConfig.PAUSE_MEDIUM) {
+		return
+	}
 	e.transitionState(StateEndHand)
 }
 
 func (e *engine) endStreet() {
-	createSidePots(e.state.psuedoDealer, e.state.currentBet, e.state.collectedPot, e.state.pot)
+	// what nobody called goes back before the side pots are built
+	e.state.returnUncalledBet()
+	e.state.buildPots()
 	e.state.collectPot()
 	e.transitionState(StatePauseAfterEndStreet)
 }
 
 func (e *engine) pauseAfterEndStreet() {
-	time.Sleep(config.AppConfig.PAUSE_MEDIUM)
+	if e.waiting(config.AppConfig.PAUSE_MEDIUM) {
+		return
+	}
+	e.nextStreet()
+}
+
+// nextStreet moves on once a street's betting is over, any discard or run it vote comes first
+func (e *engine) nextStreet() {
 	if e.state.isStreetRiver() {
 		e.transitionState(StateShowdown)
+	} else if e.state.isDiscardDue() {
+		e.state.startDiscard()
+		e.transitionState(StateDiscard)
+	} else if e.state.isRunItVoteDue() {
+		e.startRunItVote()
+	} else if e.state.variant.draw {
+		e.state.goToNextStreet()
+		e.state.startDraw()
+		e.transitionState(StateDraw)
 	} else {
 		e.state.goToNextStreet()
 		e.transitionState(StateDealStreet)
 	}
 }
 
-// returns true if all players are all in
+// betting waits until everyone has discarded or time runs out
+func (e *engine) whileDiscarding() {
+	e.processGameCommand()
+	if !e.state.everyoneDiscarded() && e.state.clock.now().Before(e.state.discardDeadline) {
+		return
+	}
+	e.state.discardForRemaining()
+	e.nextStreet()
+}
+
+// once nobody can bet any more the players still in the hand vote on how many times to run the board
+func (e *engine) startRunItVote() {
+	e.state.spotlight = nil
+	e.state.runItDeadline = e.state.clock.now().Add(config.AppConfig.RUN_IT_TIMEOUT)
+	pointer := e.state.psuedoDealer
+	for {
+		pointer.runItVote = 0
+		pointer = pointer.nextInHand
+		if pointer == e.state.psuedoDealer {
+			break
+		}
+	}
+	e.transitionState(StateRunItVote)
+}
+
+func (e *engine) runItVote() {
+	// only runIt votes mean anything here, anything else is answered by the player handlers
+	e.processGameCommand()
+	if !e.state.isRunItVoteComplete() && e.state.clock.now().Before(e.state.runItDeadline) {
+		return
+	}
+
+	e.state.resolveRunItVote()
+	log.Println("Running it", e.state.runs, "times in room", e.roomName)
+	e.state.goToNextStreet()
+	e.transitionState(StateDealStreet)
+}
+
+// returns true if nobody is left to act, either everyone is all in or only one player isn't
 func (e *engine) resetSpotlight() bool {
+	if e.state.isBettingClosed() {
+		return true
+	}
+	if e.state.variant.stud {
+		e.state.resetStudSpotlight()
+		return false
+	}
 	e.state.spotlight = e.state.psuedoDealer.nextInHand
 	for e.state.spotlight.isAllIn() {
 		e.state.spotlight = e.state.spotlight.nextInHand
-		if e.state.spotlight == e.state.psuedoDealer.nextInHand {
-			return true
-		}
 	}
 
 	e.state.lastAggressor = e.state.psuedoDealer.nextInHand
 	e.state.minRaise = e.state.bigBlind
+	e.state.bets = 0
 	return false
 }
 
 func (e *engine) dealStreet() {
-	var cards []poker.Card
-	if e.state.isStreetFlop() {
-		cards = append(cards, e.state.deck.Draw(3)...)
-	} else {
-		cards = append(cards, e.state.deck.Draw(1)...)
+	// every board draws its own cards, when running it more than once the boards split here
+	if e.state.variant.stud {
+		e.state.dealStudStreet()
+	} else if !e.state.variant.draw {
+		for i := range e.state.boards {
+			if e.state.isStreetFlop() {
+				e.state.boards[i] = append(e.state.boards[i], e.state.deck.draw(3)...)
+			} else {
+				e.state.boards[i] = append(e.state.boards[i], e.state.deck.draw(1)...)
+			}
+		}
 	}
-	e.state.communityCards = append(e.state.communityCards, cards...)
 
-	// if all players are all in, skip to end street
-	isAllPlayersAllIn := e.resetSpotlight()
-	if isAllPlayersAllIn {
+	// if no one can act, skip to end street
+	isBettingClosed := e.resetSpotlight()
+	if isBettingClosed {
 		e.transitionState(StateEndStreet)
 	} else {
 		e.transitionState(StateProcessGameCommands)
@@ -296,48 +694,225 @@ func (e *engine) dealStreet() {
 }
 
 func (e *engine) showdown() {
-	winners := findBestHand(e.state.psuedoDealer, e.state.communityCards)
-	e.state.payoutWinners(winners)
-
-	// remove winners in case we still need to payout a side pot
-	e.state.removePlayersInHand(winners)
+	if e.state.payouts == nil {
+		e.state.startShowdown()
+	}
+	if len(e.state.pots) == 0 {
+		e.transitionState(StateEndHand)
+		return
+	}
+	// one pot at a time, a pot with one player left in it isn't contested
+	payout := e.state.currentPayout()
+	pot := e.state.pots[e.state.showdownPot]
+	board := e.state.boards[payout.board]
+	winners := pot.eligible
+	if len(winners) > 1 {
+		winners = findBestHand(winners, board, e.state.payoutEvaluator(payout))
+		for _, winner := range winners {
+			log.Println(winner.user, "has", e.state.variant.describe(e.state.variant.evaluate(winner.holeCards, winner.handBoard(board))), "low:", payout.low)
+		}
+	}
+	e.state.payPot(winners, pot.amount/float64(len(e.state.payouts)))
 	e.transitionState(StatePauseAfterShowdown)
 }
 
 func (e *engine) pauseAfterShowdown() {
-	time.Sleep(config.AppConfig.PAUSE_MEDIUM)
+	e.processGameCommand()
+	if e.waiting(config.AppConfig.PAUSE_MEDIUM) {
+		return
+	}
 
-	// continue to pay sidepots until the pot is empty or no players remain
-	if e.state.pot > 0 && e.state.psuedoDealer != nil {
+	if e.state.nextShowdownPot() {
 		e.transitionState(StateShowdown)
 	} else {
 		e.transitionState(StateEndHand)
 	}
 }
 
+// isBettingOver is true from the last fold or the showdown until the hand ends, players can show
+// their cards but nothing else
+func isBettingOver(engineState engineState) bool {
+	switch engineState {
+	case StatePauseAfterEveryoneFolded, StateEveryoneFoldedPayout, StatePauseAfterEveryoneFoldedPayout, StateShowdown, StatePauseAfterShowdown:
+		return true
+	}
+	return false
+}
+
 func (e *engine) endHand() {
+	// anything still queued was meant for the hand that just ended
+	e.gameCommands = make([]Event, 0)
 	e.state.resetState()
+	if e.state.isRotationDue() {
+		e.state.rotateGame()
+		log.Println("Switching room", e.roomName, "to", e.state.variant.name, e.state.structure)
+	}
 	e.processSitCommand()
+	e.saveSnapshot()
 	e.transitionState(StatePauseAfterEndHand)
 }
 
 func (e *engine) pauseAfterEndHand() {
-	time.Sleep(config.AppConfig.PAUSE_LONG)
+	if e.waiting(config.AppConfig.PAUSE_LONG) {
+		return
+	}
+	e.startNextHand()
+}
+
+// a paused table holds here between hands until it's resumed, on a dealer's choice table a
+// new orbit waits for the game to be chosen
+func (e *engine) startNextHand() {
+	if e.state.paused {
+		e.transitionState(StatePaused)
+	} else if e.state.isGameChoiceDue() {
+		if err := e.state.startGameChoice(); err != nil {
+			log.Println("Error starting game choice: ", err)
+			e.transitionState(StateProcessSitCommands)
+			return
+		}
+		e.transitionState(StateChooseGame)
+	} else {
+		e.transitionState(StateStartHand)
+	}
+}
+
+// while the game is being chosen only the choice is processed, everything else waits for the
+// hand to start like it would while paused
+func (e *engine) whileChoosingGame() {
+	remaining := make([]Event, 0)
+	for _, command := range e.sitCommands {
+		if command.EngineCommand != "chooseGame" {
+			remaining = append(remaining, command)
+			continue
+		}
+		if err := e.handleSitCommand(command); err != nil {
+			log.Println("Error processing sit command: ", err)
+			e.sendError(command.User, err)
+		}
+	}
+	e.sitCommands = remaining
+
+	if !e.state.gameChosen && e.state.clock.now().Before(e.state.chooseDeadline) {
+		return
+	}
+	e.state.resolveGameChoice()
+	log.Println("Playing", e.state.variant.name, e.state.structure, "in room", e.roomName)
 	e.transitionState(StateStartHand)
 }
 
+// while paused only pause/resume commands are processed, everything else stays queued so
+// seats and stacks are frozen until play resumes
+func (e *engine) whilePaused() {
+	remaining := make([]Event, 0)
+	for _, command := range e.sitCommands {
+		if !pausedCommands[command.EngineCommand] {
+			remaining = append(remaining, command)
+			continue
+		}
+		if err := e.handleSitCommand(command); err != nil {
+			log.Println("Error processing sit command: ", err)
+			e.sendError(command.User, err)
+		}
+	}
+	e.sitCommands = remaining
+
+	if !e.state.paused {
+		e.startNextHand()
+	}
+}
+
+// table settings can still be changed while paused since they don't move seats or stacks
+var pausedCommands = map[string]bool{
+	"pauseGame":  true,
+	"resumeGame": true,
+	"setBuyIn":   true,
+	"setBlinds":  true,
+}
+
+func (e *engine) processPauseCommand(command Event) {
+	e.state.paused = command.EngineCommand == "pauseGame"
+	log.Println("Room", e.roomName, "paused:", e.state.paused)
+}
+
+// refund puts every stack back where it was when the hand started, anything already paid out of
+// the pots is taken back from whoever won it first
+func (e *engine) refund() {
+	committed := 0.0
+	for _, p := range e.state.players {
+		committed += p.chipsInHand
+	}
+	// the history only has wins for a hand that's still going while someone has chips in it
+	if committed > 0 {
+		for _, action := range e.state.history {
+			if p, ok := e.state.players[action.User]; ok && action.Action == "win" {
+				p.chips -= action.Amount
+			}
+		}
+		for _, p := range e.state.players {
+			p.chips += p.chipsInHand
+		}
+	}
+	e.state.pot = 0
+	e.state.resetState()
+	for _, p := range e.state.players {
+		p.chipsInPot = 0
+	}
+}
+
+// forceStop ends the hand in progress, refunds it and tells the backend the table is closed
+func (e *engine) forceStop() {
+	e.mu.Lock()
+	e.refund()
+	e.transitionState(StateProcessSitCommands)
+	e.sendState()
+	e.sendMessage(map[string]string{"channelCommand": "engineStopped"})
+	conn := e.conn
+	// stopping before the lock goes means the run loop can't get one more tick in
+	e.stop()
+	e.mu.Unlock()
+
+	deleteSnapshot(e.roomName)
+	conn.Close()
+}
+
+// waiting holds the engine in a pause state until the pause is over, the deadline runs from
+// when the engine got there so a tick only ever checks it and never sleeps
+func (e *engine) waiting(pause time.Duration) bool {
+	return e.state.clock.now().Before(e.enteredState.Add(pause))
+}
+
 func (e *engine) sendState() {
 	if !e.state.hasStateChanged() {
 		return
 	}
 
+	e.sendMessage(e.serializeState())
+	// the state only has the cards players have shown, everyone sees their own in their view
+	for _, p := range e.state.players {
+		if p.bot == nil {
+			e.sendMessage(createSerializePlayerView(e.state, p))
+		}
+	}
+	log.Println("Sending state...")
+}
+
+func (e *engine) serializeState() SerializeState {
 	betweenHands := e.engineState == StateProcessSitCommands || e.state.street == BetweenHands
-	serializeState := createSerializeState(e.state, betweenHands)
-	responseMsg, err := json.Marshal(serializeState)
+	return createSerializeState(e.state, betweenHands, e.engineState)
+}
+
+func (e *engine) sendError(user string, err error) {
+	e.sendMessage(createSerializeError(user, err))
+}
+
+func (e *engine) sendMessage(message any) {
+	if e.conn == nil {
+		return
+	}
+	responseMsg, err := json.Marshal(message)
 	if err != nil {
 		return
 	}
 
 	e.conn.WriteMessage(websocket.TextMessage, responseMsg)
-	log.Println("Sending state...")
 }
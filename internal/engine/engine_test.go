@@ -2,6 +2,10 @@ package engine
 
 import (
 	"testing"
+	"time"
+
+	"github.com/chehsunliu/poker"
+	"github.com/wegman7/game-engine/config"
 )
 
 func TestDealCards(t *testing.T) {
@@ -35,4 +39,919 @@ func TestDealCards(t *testing.T) {
 		}
 		pointer = pointer.nextInHand
 	}
-}
\ No newline at end of file
+}
+
+func TestRefund(t *testing.T) {
+	s := createState(1, 2, 30)
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state: s,
+	}
+	s.performDealerRotation()
+	p1.putChipsInPot(s, 10)
+	p2.putChipsInPot(s, 30)
+	s.collectPot()
+	p3.putChipsInPot(s, 50)
+
+	e.refund()
+	if p1.chips != 100 || p2.chips != 100 || p3.chips != 100 {
+		t.Errorf("Expected 100, 100, 100, got %v, %v, %v", p1.chips, p2.chips, p3.chips)
+	}
+	if s.pot != 0 || p3.chipsInPot != 0 || s.street != BetweenHands {
+		t.Errorf("Expected hand to be reset, got pot %v, chipsInPot %v, street %v", s.pot, p3.chipsInPot, s.street)
+	}
+}
+
+func TestRefundDuringShowdown(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 30})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	// everyone is all in preflop, the main pot is paid before the side pot
+	for i := 0; i < 100 && e.engineState != StatePauseAfterShowdown; i++ {
+		if e.engineState == StateProcessGameCommands {
+			command := Event{EngineCommand: "bet", User: s.spotlight.user, Chips: s.spotlight.chips + s.spotlight.chipsInPot}
+			if s.currentBet >= command.Chips {
+				command.EngineCommand = "call"
+			}
+			e.gameCommands = append(e.gameCommands, command)
+		}
+		e.tick()
+	}
+	if e.engineState != StatePauseAfterShowdown || len(s.pots) != 2 {
+		t.Fatalf("Expected a main pot and a side pot at the showdown, got %v with %v pots", e.engineState, len(s.pots))
+	}
+
+	e.refund()
+	if p1.chips != 30 || p2.chips != 100 || p3.chips != 100 {
+		t.Errorf("Expected 30, 100, 100, got %v, %v, %v", p1.chips, p2.chips, p3.chips)
+	}
+	if s.pot != 0 || s.street != BetweenHands {
+		t.Errorf("Expected hand to be reset, got pot %v, street %v", s.pot, s.street)
+	}
+}
+
+func TestStopBeatsTheRunLoop(t *testing.T) {
+	config.AppConfig.MAX_PLAYERS = 9
+	e := createEngine(nil, StartGameRequest{SmallBlind: 1, BigBlind: 2, Owner: "user1"})
+	for _, join := range []Event{
+		{EngineCommand: "join", SeatId: 1, User: "user1", Chips: 100},
+		{EngineCommand: "join", SeatId: 5, User: "user2", Chips: 100},
+	} {
+		if err := e.handleSitCommand(join); err != nil {
+			t.Fatalf("Expected %v to join, got %v", join.User, err)
+		}
+	}
+
+	// the run loop is waiting on the lock when the table stops, the game queued behind it mustn't start
+	e.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		e.run()
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	e.sitCommands = append(e.sitCommands, Event{EngineCommand: "startGame", User: "user1"})
+	e.stop()
+	e.mu.Unlock()
+	<-done
+	if e.engineState != StateProcessSitCommands || len(e.sitCommands) != 1 {
+		t.Errorf("Expected the stopped engine to leave startGame queued, got %v with %v queued", e.engineState, len(e.sitCommands))
+	}
+}
+
+func TestPauseFreezesSitCommands(t *testing.T) {
+	s := createState(1, 2, 30)
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	s.addPlayer(p1)
+
+	e := &engine{
+		state:       s,
+		engineState: StatePauseAfterEndHand,
+	}
+	e.sitCommands = append(e.sitCommands, Event{EngineCommand: "pauseGame", User: "user1"})
+	e.processSitCommand()
+	e.startNextHand()
+	if e.engineState != StatePaused || !s.paused {
+		t.Fatalf("Expected table to be paused, got %v", e.engineState)
+	}
+
+	e.sitCommands = append(e.sitCommands, Event{EngineCommand: "addChips", User: "user1", Chips: 50})
+	e.whilePaused()
+	if p1.chips != 100 || len(e.sitCommands) != 1 {
+		t.Errorf("Expected addChips to stay queued while paused, got chips %v, queued %v", p1.chips, len(e.sitCommands))
+	}
+
+	e.sitCommands = append(e.sitCommands, Event{EngineCommand: "resumeGame", User: "user1"})
+	e.whilePaused()
+	if e.engineState != StateStartHand || s.paused {
+		t.Errorf("Expected table to resume, got %v", e.engineState)
+	}
+	if len(e.sitCommands) != 1 || e.sitCommands[0].EngineCommand != "addChips" {
+		t.Errorf("Expected addChips to still be queued, got %v", e.sitCommands)
+	}
+}
+
+func TestOwnerCommands(t *testing.T) {
+	config.AppConfig.MAX_PLAYERS = 9
+	e := &engine{
+		state:       createTableState(StartGameRequest{RoomName: "room1", SmallBlind: 1, BigBlind: 2, Owner: "owner", Private: true}),
+		engineState: StateProcessSitCommands,
+	}
+
+	e.sitCommands = []Event{
+		{EngineCommand: "join", User: "owner", SeatId: 0, Chips: 100},
+		{EngineCommand: "join", User: "user1", SeatId: 3, Chips: 100},
+		{EngineCommand: "startGame", User: "user1"},
+	}
+	e.processSitCommand()
+	if _, ok := e.state.players["owner"]; !ok {
+		t.Errorf("Expected owner to be seated without approval")
+	}
+	if _, ok := e.state.players["user1"]; ok {
+		t.Errorf("Expected user1 to wait for approval")
+	}
+	if _, ok := e.state.pendingJoins["user1"]; !ok {
+		t.Errorf("Expected user1 to have a pending join request")
+	}
+	if e.engineState != StateProcessSitCommands {
+		t.Errorf("Expected non-owner startGame to be rejected, got %v", e.engineState)
+	}
+
+	e.sitCommands = []Event{
+		{EngineCommand: "approveJoin", User: "user1", Target: "user1"},
+		{EngineCommand: "approveJoin", User: "owner", Target: "user1"},
+	}
+	e.processSitCommand()
+	if p, ok := e.state.players["user1"]; !ok || p.seatId != 3 {
+		t.Fatalf("Expected user1 to be seated in seat 3 after approval")
+	}
+
+	e.sitCommands = []Event{
+		{EngineCommand: "setBlinds", User: "owner", SmallBlind: 2, BigBlind: 4},
+		{EngineCommand: "kick", User: "owner", Target: "user1"},
+	}
+	e.processSitCommand()
+	if _, ok := e.state.players["user1"]; ok {
+		t.Errorf("Expected user1 to be kicked")
+	}
+	e.state.applyPendingBlinds()
+	if e.state.smallBlind != 2 || e.state.bigBlind != 4 {
+		t.Errorf("Expected blinds 2/4, got %v/%v", e.state.smallBlind, e.state.bigBlind)
+	}
+}
+
+func TestRunItTwice(t *testing.T) {
+	config.AppConfig.DEBUG = false
+	config.AppConfig.MAX_RUNS = 3
+	s := createState(1, 2, 30)
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state: s,
+	}
+	s.performDealerRotation()
+	s.boards = [][]poker.Card{{poker.NewCard("2c"), poker.NewCard("3c"), poker.NewCard("7h")}}
+	p1.holeCards = []poker.Card{poker.NewCard("As"), poker.NewCard("Ad")}
+	p2.holeCards = []poker.Card{poker.NewCard("Ks"), poker.NewCard("Kd")}
+	p1.putChipsInPot(s, 100)
+	p2.putChipsInPot(s, 100)
+	s.collectPot()
+
+	p1.runItVote = 3
+	p2.runItVote = 2
+	s.resolveRunItVote()
+	if s.runs != 2 || len(s.boards) != 2 || len(s.boards[1]) != 3 {
+		t.Fatalf("Expected two copies of the flop, got %v", s.boards)
+	}
+
+	// p1 holds up on the first board, p2 hits a set on the second
+	s.boards[0] = append(s.boards[0], poker.NewCard("8h"), poker.NewCard("9d"))
+	s.boards[1] = append(s.boards[1], poker.NewCard("Kh"), poker.NewCard("Jd"))
+	e.engineState = StateShowdown
+	for i := 0; i < 10 && e.engineState != StateEndHand; i++ {
+		e.tick()
+	}
+	if e.engineState != StateEndHand {
+		t.Fatalf("Expected showdown to finish, got %v", e.engineState)
+	}
+	if p1.chips != 100 || p2.chips != 100 {
+		t.Errorf("Expected 100, 100, got %v, %v", p1.chips, p2.chips)
+	}
+}
+
+func TestBombPot(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, BombPotEvery: 1, BombPotAnte: 5})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 3})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands; i++ {
+		e.tick()
+	}
+	if e.engineState != StateProcessGameCommands || s.street != Flop {
+		t.Fatalf("Expected action to start on the flop, got %v on street %v", e.engineState, s.street)
+	}
+	if !s.bombPot || len(s.boards[0]) != 3 {
+		t.Errorf("Expected a bomb pot with the flop dealt, got %v", s.boards)
+	}
+	if s.pot != 13 || p1.chips != 95 || p2.chips != 95 || p3.chips != 0 {
+		t.Errorf("Expected pot 13 and stacks 95, 95, 0, got %v, %v, %v, %v", s.pot, p1.chips, p2.chips, p3.chips)
+	}
+	if len(s.pots) != 2 || s.pots[0].amount != 9 || len(s.pots[0].eligible) != 3 || s.pots[1].amount != 4 || len(s.pots[1].eligible) != 2 {
+		t.Errorf("Expected a main pot of 9 for everyone and a side pot of 4 for the big stacks, got %+v", s.pots)
+	}
+}
+
+func TestDoubleBoardShowdown(t *testing.T) {
+	config.AppConfig.DEBUG = false
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "doubleBoardHoldem"})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	e.tick()
+	if len(s.boards) != 2 {
+		t.Fatalf("Expected two boards, got %v", len(s.boards))
+	}
+
+	p1.holeCards = []poker.Card{poker.NewCard("As"), poker.NewCard("Ad")}
+	p2.holeCards = []poker.Card{poker.NewCard("Ks"), poker.NewCard("Kd")}
+	p3.holeCards = []poker.Card{poker.NewCard("Qs"), poker.NewCard("Qd")}
+	p1.putChipsInPot(s, 100)
+	p2.putChipsInPot(s, 100)
+	p3.putChipsInPot(s, 100)
+	s.collectPot()
+
+	// p1 wins the top board, p2 makes a set on the bottom board, p3 gets nothing
+	s.boards[0] = []poker.Card{poker.NewCard("2c"), poker.NewCard("3c"), poker.NewCard("7h"), poker.NewCard("8h"), poker.NewCard("9d")}
+	s.boards[1] = []poker.Card{poker.NewCard("2c"), poker.NewCard("3c"), poker.NewCard("7h"), poker.NewCard("Kh"), poker.NewCard("Jd")}
+	e.engineState = StateShowdown
+	for i := 0; i < 10 && e.engineState != StateEndHand; i++ {
+		e.tick()
+	}
+	if p1.chips != 150 || p2.chips != 150 || p3.chips != 0 {
+		t.Errorf("Expected 150, 150, 0, got %v, %v, %v", p1.chips, p2.chips, p3.chips)
+	}
+}
+
+func TestOmahaHiLoQuartering(t *testing.T) {
+	config.AppConfig.DEBUG = false
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "omahaHiLo"})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	e.tick()
+
+	// p1 and p2 tie for low with 8-5-3-2-A, p2 also wins high with trip kings
+	s.boards[0] = []poker.Card{poker.NewCard("2c"), poker.NewCard("5d"), poker.NewCard("8h"), poker.NewCard("Kc"), poker.NewCard("Ks")}
+	p1.holeCards = []poker.Card{poker.NewCard("Ac"), poker.NewCard("3d"), poker.NewCard("Qh"), poker.NewCard("Qd")}
+	p2.holeCards = []poker.Card{poker.NewCard("Ad"), poker.NewCard("3h"), poker.NewCard("Kh"), poker.NewCard("7s")}
+	p3.holeCards = []poker.Card{poker.NewCard("Jc"), poker.NewCard("Jd"), poker.NewCard("9h"), poker.NewCard("9s")}
+	p1.putChipsInPot(s, 100)
+	p2.putChipsInPot(s, 100)
+	p3.putChipsInPot(s, 100)
+	s.collectPot()
+
+	e.engineState = StateShowdown
+	for i := 0; i < 10 && e.engineState != StateEndHand; i++ {
+		e.tick()
+	}
+	if p1.chips != 75 || p2.chips != 225 || p3.chips != 0 {
+		t.Errorf("Expected 75, 225, 0, got %v, %v, %v", p1.chips, p2.chips, p3.chips)
+	}
+}
+
+func TestStudBringIn(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 4, Ante: 0.5, Game: "stud"})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands; i++ {
+		e.tick()
+	}
+	if s.pot != 2.5 || s.currentBet != 1 || s.minRaise != 3 {
+		t.Fatalf("Expected antes and a bring in of 1, got pot %v bet %v", s.pot, s.currentBet)
+	}
+	for _, p := range []*player{p1, p2, p3} {
+		if len(p.holeCards) != 2 || len(p.upCards) != 1 {
+			t.Fatalf("Expected two down and one up, got %v and %v", p.holeCards, p.upCards)
+		}
+	}
+
+	// the deuce of clubs is the lowest card in the deck, in razz the king of spades is the highest
+	p1.upCards = []poker.Card{poker.NewCard("2d")}
+	p2.upCards = []poker.Card{poker.NewCard("Ks")}
+	p3.upCards = []poker.Card{poker.NewCard("2c")}
+	if bringIn := s.findBringIn(); bringIn != p3 {
+		t.Errorf("Expected the deuce of clubs to bring it in, got %v", bringIn.user)
+	}
+	s.variant = variants["razz"]
+	if bringIn := s.findBringIn(); bringIn != p2 {
+		t.Errorf("Expected the king to bring it in in razz, got %v", bringIn.user)
+	}
+
+	// the best board showing acts first from fourth street on
+	s.variant = variants["stud"]
+	p1.upCards = []poker.Card{poker.NewCard("2d"), poker.NewCard("2s")}
+	p2.upCards = []poker.Card{poker.NewCard("As"), poker.NewCard("Ks")}
+	p3.upCards = []poker.Card{poker.NewCard("7c"), poker.NewCard("4h")}
+	s.resetStudSpotlight()
+	if s.spotlight != p1 {
+		t.Errorf("Expected the pair showing to act first, got %v", s.spotlight.user)
+	}
+	s.variant = variants["razz"]
+	s.resetStudSpotlight()
+	if s.spotlight != p3 {
+		t.Errorf("Expected the lowest board to act first in razz, got %v", s.spotlight.user)
+	}
+}
+
+func TestStudStreets(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "razz"})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	// everyone calls or checks down to showdown
+	for i := 0; i < 100 && e.engineState != StateShowdown; i++ {
+		e.tick()
+		if err := s.checkInvariants(e.engineState); err != nil {
+			t.Fatal(err)
+		}
+		if e.engineState == StateProcessGameCommands {
+			command := "check"
+			if s.spotlight.chipsInPot < s.currentBet {
+				command = "call"
+			}
+			e.gameCommands = append(e.gameCommands, Event{EngineCommand: command, User: s.spotlight.user})
+		}
+	}
+	if e.engineState != StateShowdown || s.street != SeventhStreet {
+		t.Fatalf("Expected to reach showdown on seventh street, got %v on street %v", e.engineState, s.street)
+	}
+	for _, p := range []*player{p1, p2} {
+		if len(p.holeCards) != 3 || len(p.upCards) != 4 {
+			t.Errorf("Expected three down and four up, got %v and %v", p.holeCards, p.upCards)
+		}
+	}
+}
+
+func TestStudDownCardsArePrivate(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "stud"})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands; i++ {
+		e.tick()
+	}
+	// the table sees everyone's upcard, only the player gets their downcards
+	for _, p := range []*player{p1, p2} {
+		public := e.serializeState().Players[p.seatId]
+		if len(public.HoleCards) != 0 || len(public.UpCards) != 1 {
+			t.Errorf("Expected only %v's upcard in the state, got %v down and %v up", p.user, public.HoleCards, public.UpCards)
+		}
+		if own := createSerializePlayerView(s, p); own.User != p.user || len(own.View.HoleCards) != 2 {
+			t.Errorf("Expected %v to get their two downcards, got %v for %v", p.user, own.View.HoleCards, own.User)
+		}
+	}
+}
+
+func TestTripleDraw(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "tripleDraw"})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	draws := 0
+	for i := 0; i < 200 && e.engineState != StateShowdown; i++ {
+		e.tick()
+		if err := s.checkInvariants(e.engineState); err != nil {
+			t.Fatal(err)
+		}
+		switch e.engineState {
+		case StateProcessGameCommands:
+			command := "check"
+			if s.spotlight.chipsInPot < s.currentBet {
+				command = "call"
+			}
+			e.gameCommands = append(e.gameCommands, Event{EngineCommand: command, User: s.spotlight.user})
+		case StateDraw:
+			if len(e.gameCommands) > 0 {
+				break
+			}
+			// betting isn't allowed during the draw
+			discard := []string{s.spotlight.holeCards[0].String(), s.spotlight.holeCards[1].String()}
+			e.gameCommands = append(e.gameCommands, Event{EngineCommand: "bet", User: s.spotlight.user, Chips: 10})
+			e.gameCommands = append(e.gameCommands, Event{EngineCommand: "discard", User: s.spotlight.user, Cards: discard})
+			draws++
+		}
+	}
+	if e.engineState != StateShowdown || s.street != ThirdDraw {
+		t.Fatalf("Expected to reach showdown after the third draw, got %v on street %v", e.engineState, s.street)
+	}
+	if draws != 6 || len(s.discards) != 12 {
+		t.Errorf("Expected two players to draw two cards three times, got %v draws and %v discards", draws, len(s.discards))
+	}
+	if p1.chips != 98 || p2.chips != 98 {
+		t.Errorf("Expected no bets during the draw, got stacks %v and %v", p1.chips, p2.chips)
+	}
+	if err := p1.verifyLegalDiscard(p2.holeCards[:1]); err == nil {
+		t.Errorf("Expected discarding a card not in the hand to fail")
+	}
+}
+
+func TestDrawReshufflesDiscards(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "tripleDraw"})
+	p := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p.holeCards = cards("2c", "3c", "4c", "5c", "7d")
+	s.deck = newDeck(cards("8d", "9d"))
+	s.discards = cards("Ks", "Qs", "Js")
+
+	s.replaceCards(p, cards("2c", "3c", "4c"))
+	if len(p.holeCards) != 5 || s.deck.remaining() != 2 {
+		t.Fatalf("Expected the stub to run out and the discards to be reshuffled, got %v with %v left", p.holeCards, s.deck.remaining())
+	}
+	for _, card := range cards("2c", "3c", "4c") {
+		if containsCard(p.holeCards, card) || containsCard(s.deck.cards, card) {
+			t.Errorf("Expected the player's own discards not to come back to them, got %v", p.holeCards)
+		}
+	}
+	if len(s.discards) != 3 {
+		t.Errorf("Expected the new discards to be kept for the next reshuffle, got %v", s.discards)
+	}
+}
+
+func TestMixedGameRotation(t *testing.T) {
+	s := createTableState(StartGameRequest{
+		SmallBlind:  1,
+		BigBlind:    2,
+		Rotation:    []RotationGame{{Game: "holdem", Structure: fixedLimit}, {Game: "stud"}},
+		RotateEvery: 1,
+	})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	if s.variant.name != "holdem" || s.structure != fixedLimit {
+		t.Fatalf("Expected to start with limit holdem, got %v %v", s.variant.name, s.structure)
+	}
+	if next, hands := s.nextGame(); next.Game != "stud" || next.Structure != fixedLimit || hands != 1 {
+		t.Errorf("Expected stud to be announced, got %v in %v hands", next, hands)
+	}
+
+	e.tick()
+	button := s.dealer
+	e.endHand()
+	if s.variant.name != "stud" || s.structure != fixedLimit {
+		t.Fatalf("Expected stud after one hand, got %v %v", s.variant.name, s.structure)
+	}
+
+	// the button stays put through the stud hand and moves on when holdem comes back
+	e.engineState = StateStartHand
+	e.tick()
+	if s.dealer != button {
+		t.Errorf("Expected the button to stay on %v during stud, got %v", button.user, s.dealer.user)
+	}
+	e.endHand()
+	if s.variant.name != "holdem" {
+		t.Fatalf("Expected holdem after the stud hand, got %v", s.variant.name)
+	}
+	e.engineState = StateStartHand
+	e.tick()
+	if s.dealer != button.next {
+		t.Errorf("Expected the button to move to %v, got %v", button.next.user, s.dealer.user)
+	}
+}
+
+func TestBettingStructures(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2})
+	p := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	s.street = Preflop
+	s.pot = 3
+	s.currentBet = 2
+	s.minRaise = 2
+
+	if err := p.verifyLegalBet(s, 3); err == nil {
+		t.Errorf("Expected a raise to 3 to be less than the minimum")
+	}
+	if err := p.verifyLegalBet(s, 50); err != nil {
+		t.Errorf("Expected any no limit raise over the minimum, got %v", err)
+	}
+	if err := p.verifyLegalBet(s, 2); err == nil {
+		t.Errorf("Expected a bet that only calls to be rejected")
+	}
+
+	// a pot sized raise calls 2 and then raises the 5 in the pot
+	s.structure = potLimit
+	if err := p.verifyLegalBet(s, 7); err != nil {
+		t.Errorf("Expected a pot sized raise to 7, got %v", err)
+	}
+	if err := p.verifyLegalBet(s, 8); err == nil {
+		t.Errorf("Expected a raise to 8 to be more than the pot")
+	}
+
+	s.structure = fixedLimit
+	if err := p.verifyLegalBet(s, 4); err != nil {
+		t.Errorf("Expected a raise to 4 preflop, got %v", err)
+	}
+	if err := p.verifyLegalBet(s, 6); err == nil {
+		t.Errorf("Expected a raise to 6 preflop to be rejected")
+	}
+	s.street = Turn
+	s.currentBet = 0
+	if err := p.verifyLegalBet(s, 4); err != nil {
+		t.Errorf("Expected a bet of 4 on the turn, got %v", err)
+	}
+	s.bets = fixedLimitCap
+	if err := p.verifyLegalBet(s, 4); err == nil {
+		t.Errorf("Expected betting to be capped")
+	}
+}
+
+func TestDealersChoice(t *testing.T) {
+	s := createTableState(StartGameRequest{
+		SmallBlind:    1,
+		BigBlind:      2,
+		Game:          "holdem",
+		DealersChoice: []RotationGame{{Game: "holdem"}, {Game: "razz"}},
+	})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+
+	e := &engine{
+		state:       s,
+		engineState: StateProcessSitCommands,
+	}
+	e.startNextHand()
+	if e.engineState != StateChooseGame || s.chooser != "user2" {
+		t.Fatalf("Expected user2 to choose the first game, got %v choosing in %v", s.chooser, e.engineState)
+	}
+
+	// only the chooser can pick and only from the table's list
+	e.sitCommands = []Event{
+		{EngineCommand: "chooseGame", User: "user1", Game: "razz"},
+		{EngineCommand: "chooseGame", User: "user2", Game: "stud"},
+		{EngineCommand: "chooseGame", User: "user2", Game: "razz"},
+		{EngineCommand: "leave", User: "user3"},
+	}
+	e.tick()
+	if e.engineState != StateStartHand || s.variant.name != "razz" || s.structure != fixedLimit {
+		t.Fatalf("Expected razz to be chosen, got %v %v in %v", s.variant.name, s.structure, e.engineState)
+	}
+	if len(e.sitCommands) != 1 {
+		t.Errorf("Expected other sit commands to wait for the hand, got %v", e.sitCommands)
+	}
+
+	// after an orbit the choice moves left, nobody picks in time so the default is played
+	e.tick()
+	s.gameHands = s.orbitSize
+	e.startNextHand()
+	if e.engineState != StateChooseGame || s.chooser != "user3" {
+		t.Fatalf("Expected user3 to choose next, got %v choosing in %v", s.chooser, e.engineState)
+	}
+	e.sitCommands = nil
+	e.tick()
+	if s.variant.name != "holdem" || e.engineState != StateStartHand {
+		t.Errorf("Expected the default game after the timeout, got %v in %v", s.variant.name, e.engineState)
+	}
+}
+
+func TestPineappleDiscard(t *testing.T) {
+	for game, discardOn := range map[string]street{"pineapple": Preflop, "crazyPineapple": Flop} {
+		s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: game})
+		p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+		p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+		s.addPlayer(p1)
+		s.addPlayer(p2)
+
+		e := &engine{
+			state:       s,
+			engineState: StateStartHand,
+		}
+		discarded := false
+		for i := 0; i < 100 && e.engineState != StateShowdown; i++ {
+			switch e.engineState {
+			case StateProcessGameCommands:
+				command := "check"
+				if s.spotlight.chipsInPot < s.currentBet {
+					command = "call"
+				}
+				e.gameCommands = append(e.gameCommands, Event{EngineCommand: command, User: s.spotlight.user})
+			case StateDiscard:
+				if s.street != discardOn {
+					t.Fatalf("Expected %v to discard on street %v, got %v", game, discardOn, s.street)
+				}
+				if len(p1.holeCards) != 3 {
+					t.Fatalf("Expected three hole cards before the discard, got %v", p1.holeCards)
+				}
+				// user1 throws away their first card, user2 runs out of time and loses their last
+				e.gameCommands = append(e.gameCommands, Event{EngineCommand: "discard", User: "user1", Cards: []string{p1.holeCards[0].String()}})
+				kept := [][]poker.Card{p1.holeCards[1:], p2.holeCards[:2]}
+				e.tick()
+				if !CompareCardSlices(p1.holeCards, kept[0]) || !CompareCardSlices(p2.holeCards, kept[1]) {
+					t.Errorf("Expected %v and %v to be kept, got %v and %v", kept[0], kept[1], p1.holeCards, p2.holeCards)
+				}
+				discarded = true
+			}
+			e.tick()
+			if err := s.checkInvariants(e.engineState); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if e.engineState != StateShowdown || !discarded {
+			t.Fatalf("Expected %v to discard and reach showdown, got %v", game, e.engineState)
+		}
+		if len(p1.holeCards) != 2 || len(p2.holeCards) != 2 || len(s.discards) != 2 {
+			t.Errorf("Expected two hole cards each at showdown, got %v and %v", p1.holeCards, p2.holeCards)
+		}
+	}
+}
+
+func TestButtonAnteAllIn(t *testing.T) {
+	// heads up the button posts the ante and the small blind, with 3 chips each the button is all
+	// in and the big blind has nothing to call so the hand runs out
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "shortDeck"})
+	s.addPlayer(createPlayer(Event{SeatId: 1, User: "user1", Chips: 3}))
+	s.addPlayer(createPlayer(Event{SeatId: 5, User: "user2", Chips: 3}))
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands && e.engineState != StateEndStreet; i++ {
+		e.tick()
+	}
+	if e.engineState != StateEndStreet {
+		t.Errorf("Expected the street to end with nobody left to act, got %v with %v to act", e.engineState, s.spotlight.user)
+	}
+
+	// three handed the button acts first preflop, all in from the ante the small blind acts instead
+	s = createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: "shortDeck"})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 2})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+	s.dealer = p2
+
+	e = &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands && e.engineState != StateEndStreet; i++ {
+		e.tick()
+	}
+	if s.dealer != p3 || e.engineState != StateProcessGameCommands || s.spotlight != p1 {
+		t.Errorf("Expected user1 to act first behind the all in button, got %v with %v to act", e.engineState, s.spotlight.user)
+	}
+}
+
+func TestFoldedSidePot(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2})
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 30})
+	p2 := createPlayer(Event{SeatId: 3, User: "user2", Chips: 50})
+	p3 := createPlayer(Event{SeatId: 5, User: "user3", Chips: 200})
+	p4 := createPlayer(Event{SeatId: 7, User: "user4", Chips: 200})
+	for _, p := range []*player{p1, p2, p3, p4} {
+		s.addPlayer(p)
+	}
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	// the short stacks call all in, the big stacks raise to 80 between them and then both fold on the flop
+	for i := 0; i < 200 && e.engineState != StatePauseAfterEndHand; i++ {
+		if e.engineState == StateProcessGameCommands {
+			command := Event{EngineCommand: "call", User: s.spotlight.user}
+			switch {
+			case s.street != Preflop:
+				command.EngineCommand = "fold"
+			case s.spotlight.chips > 100 && s.currentBet < 80:
+				command = Event{EngineCommand: "bet", User: s.spotlight.user, Chips: 80}
+			}
+			e.gameCommands = append(e.gameCommands, command)
+		}
+		e.tick()
+		if err := s.checkInvariants(e.engineState); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.engineState != StatePauseAfterEndHand {
+		t.Fatalf("Expected the hand to end, got %v", e.engineState)
+	}
+	// the 60 the big stacks put in over the short stacks' 50 goes back to whoever folded last
+	if p1.chips+p2.chips != 180 || p3.chips+p4.chips != 300 {
+		t.Errorf("Expected 180 for the short stacks and 300 for the big stacks, got %v and %v", p1.chips+p2.chips, p3.chips+p4.chips)
+	}
+}
+
+func TestAllInAgainstOneStackRunsOut(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 10})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands; i++ {
+		e.tick()
+	}
+	// the short stack is all in once the raise is called, there's nobody left for the other to bet against
+	first, second := s.spotlight, s.spotlight.nextInHand
+	e.gameCommands = append(e.gameCommands,
+		Event{EngineCommand: "bet", User: first.user, Chips: 10},
+		Event{EngineCommand: "call", User: second.user},
+	)
+	for i := 0; i < 50 && e.engineState != StateShowdown; i++ {
+		e.tick()
+	}
+	if e.engineState != StateShowdown || len(s.boards[0]) != 5 {
+		t.Errorf("Expected the board to run out to showdown, got %v on %v with %v to act", e.engineState, s.boards[0], s.spotlight.user)
+	}
+}
+
+func TestPausesWaitForTheClock(t *testing.T) {
+	config.AppConfig.PAUSE_MEDIUM = time.Second
+	config.AppConfig.RUN_IT_TIMEOUT = 5 * time.Second
+	config.AppConfig.MAX_RUNS = 3
+	defer func() {
+		config.AppConfig.PAUSE_MEDIUM = 0
+		config.AppConfig.RUN_IT_TIMEOUT = 0
+	}()
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2, RunItTwice: true})
+	clock := newFakeClock()
+	s.clock = clock
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	e.tick()
+	e.tick()
+	if e.engineState != StatePauseAfterStartHand {
+		t.Fatalf("Expected to wait after starting the hand, got %v", e.engineState)
+	}
+	clock.advance(999 * time.Millisecond)
+	e.tick()
+	if e.engineState != StatePauseAfterStartHand {
+		t.Fatalf("Expected to wait a whole second, got %v", e.engineState)
+	}
+	clock.advance(time.Millisecond)
+	e.tick()
+	if e.engineState != StatePostBlinds {
+		t.Fatalf("Expected the blinds once the pause is over, got %v", e.engineState)
+	}
+
+	// both players get it all in preflop, only one of them votes on running it more than once
+	for i := 0; i < 20 && e.engineState != StateRunItVote; i++ {
+		if e.engineState == StateProcessGameCommands {
+			command := Event{EngineCommand: "bet", User: s.spotlight.user, Chips: 100}
+			if s.currentBet == 100 {
+				command.EngineCommand = "call"
+			}
+			e.gameCommands = append(e.gameCommands, command)
+		}
+		clock.advance(time.Second)
+		e.tick()
+	}
+	if e.engineState != StateRunItVote {
+		t.Fatalf("Expected a run it vote, got %v", e.engineState)
+	}
+	e.gameCommands = append(e.gameCommands, Event{EngineCommand: "runIt", User: "user1", Runs: 2})
+	e.tick()
+	clock.advance(4 * time.Second)
+	e.tick()
+	if e.engineState != StateRunItVote {
+		t.Fatalf("Expected to wait for the second vote, got %v", e.engineState)
+	}
+	clock.advance(time.Second)
+	e.tick()
+	if e.engineState != StateDealStreet || s.runs != 1 {
+		t.Errorf("Expected the vote to time out and run it once, got %v running it %v times", e.engineState, s.runs)
+	}
+}
+
+func TestLeftoverCommandsAreRejected(t *testing.T) {
+	s := createTableState(StartGameRequest{SmallBlind: 1, BigBlind: 2})
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+
+	e := &engine{
+		state:       s,
+		engineState: StateStartHand,
+	}
+	for i := 0; i < 20 && e.engineState != StateProcessGameCommands; i++ {
+		e.tick()
+	}
+	// the small blind's fold was sent before the big blind's check closed preflop, it mustn't be played
+	sb, bb := s.spotlight, s.spotlight.nextInHand
+	e.gameCommands = append(e.gameCommands,
+		Event{EngineCommand: "call", User: sb.user},
+		Event{EngineCommand: "check", User: bb.user},
+		Event{EngineCommand: "fold", User: sb.user},
+	)
+	for i := 0; i < 20 && (s.street != Flop || e.engineState != StateProcessGameCommands); i++ {
+		e.tick()
+	}
+	if s.street != Flop || s.spotlight != bb || sb.nextInHand == nil || len(e.gameCommands) != 0 {
+		t.Errorf("Expected the fold turned down and %v first to act on the flop, got street %v with %v to act", bb.user, s.street, s.spotlight.user)
+	}
+}
+
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"math"
+	"sort"
+
+	"github.com/chehsunliu/poker"
+)
+
+// every evaluator returns a rank where lower is better, the same as poker.Evaluate
+type evaluator func(holeCards []poker.Card, board []poker.Card) int32
+
+// lowEvaluator also reports whether the hand qualifies for the low half at all
+type lowEvaluator func(holeCards []poker.Card, board []poker.Card) (int32, bool)
+
+// anything that doesn't qualify for low ranks behind every hand that does
+const noLowQualifier = int32(1 << 20)
+
+func evaluateHoldem(holeCards []poker.Card, board []poker.Card) int32 {
+	cards := make([]poker.Card, 0, len(holeCards)+len(board))
+	cards = append(cards, holeCards...)
+	return poker.Evaluate(append(cards, board...))
+}
+
+// omaha hands must use exactly two hole cards and three from the board
+func evaluateOmaha(holeCards []poker.Card, board []poker.Card) int32 {
+	best := int32(math.MaxInt32)
+	for _, hand := range omahaHands(holeCards, board) {
+		best = min(best, poker.Evaluate(hand))
+	}
+	return best
+}
+
+// eight or better: five different ranks eight or lower, aces play low, straights and flushes don't count
+func evaluateOmahaLow(holeCards []poker.Card, board []poker.Card) (int32, bool) {
+	best := noLowQualifier
+	for _, hand := range omahaHands(holeCards, board) {
+		if rank, ok := eightOrBetter(hand); ok {
+			best = min(best, rank)
+		}
+	}
+	return best, best != noLowQualifier
+}
+
+// stud eight or better plays any five of the player's cards for low
+func evaluateStudLow(holeCards []poker.Card, board []poker.Card) (int32, bool) {
+	cards := make([]poker.Card, 0, len(holeCards)+len(board))
+	cards = append(cards, holeCards...)
+	cards = append(cards, board...)
+
+	best := noLowQualifier
+	for _, hand := range combinations(cards, 5) {
+		if rank, ok := eightOrBetter(hand); ok {
+			best = min(best, rank)
+		}
+	}
+	return best, best != noLowQualifier
+}
+
+func eightOrBetter(hand []poker.Card) (int32, bool) {
+	ranks := lowRanks(hand)
+	seen := make(map[int32]bool)
+	for _, rank := range ranks {
+		if rank > 8 || seen[rank] {
+			return 0, false
+		}
+		seen[rank] = true
+	}
+	return lowRankKey(ranks), true
+}
+
+// lowRanks gives the ace-low rank (ace = 1, king = 13) of each card, highest first
+func lowRanks(cards []poker.Card) []int32 {
+	ranks := make([]int32, len(cards))
+	for i, card := range cards {
+		ranks[i] = (card.Rank()+1)%13 + 1
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		return ranks[i] > ranks[j]
+	})
+	return ranks
+}
+
+// lowRankKey compares low hands from the highest card down, 8-6-5-4-3 loses to 8-6-4-3-2
+func lowRankKey(ranks []int32) int32 {
+	key := int32(0)
+	for _, rank := range ranks {
+		key = key*16 + rank
+	}
+	return key
+}
+
+func omahaHands(holeCards []poker.Card, board []poker.Card) [][]poker.Card {
+	hands := make([][]poker.Card, 0)
+	for _, fromHole := range combinations(holeCards, 2) {
+		for _, fromBoard := range combinations(board, 3) {
+			hand := make([]poker.Card, 0, 5)
+			hand = append(hand, fromHole...)
+			hands = append(hands, append(hand, fromBoard...))
+		}
+	}
+	return hands
+}
+
+func combinations(cards []poker.Card, k int) [][]poker.Card {
+	if k == 0 {
+		return [][]poker.Card{{}}
+	}
+	if len(cards) < k {
+		return nil
+	}
+
+	result := make([][]poker.Card, 0)
+	for _, rest := range combinations(cards[1:], k-1) {
+		combination := make([]poker.Card, 0, k)
+		combination = append(combination, cards[0])
+		result = append(result, append(combination, rest...))
+	}
+	return append(result, combinations(cards[1:], k)...)
+}
+
+// short deck ranks a flush above a full house, the class order here replaces poker.RankClass order
+var shortDeckClassOrder = map[int32]int32{
+	1: 0, // straight flush
+	2: 1, // four of a kind
+	4: 2, // flush
+	3: 3, // full house
+	5: 4, // straight
+	6: 5, // three of a kind
+	7: 6, // two pair
+	8: 7, // pair
+	9: 8, // high card
+}
+
+// every class spans fewer ranks than this, so class*shortDeckClassSize+rank keeps classes apart
+const shortDeckClassSize = 8000
+
+// the lowest short deck straight, A-6-7-8-9, ranks where 9-high does in a full deck
+const (
+	shortDeckWheelStraight      = int32(1605)
+	shortDeckWheelStraightFlush = int32(6)
+)
+
+func evaluateShortDeck(holeCards []poker.Card, board []poker.Card) int32 {
+	cards := make([]poker.Card, 0, len(holeCards)+len(board))
+	cards = append(cards, holeCards...)
+	cards = append(cards, board...)
+
+	best := int32(math.MaxInt32)
+	for _, hand := range combinations(cards, 5) {
+		rank := poker.Evaluate(hand)
+		if isShortDeckWheel(hand) {
+			rank = shortDeckWheelStraight
+			if isFlush(hand) {
+				rank = shortDeckWheelStraightFlush
+			}
+		}
+		best = min(best, shortDeckClassOrder[poker.RankClass(rank)]*shortDeckClassSize+rank)
+	}
+	return best
+}
+
+func describeShortDeck(rank int32) string {
+	return poker.RankString(rank % shortDeckClassSize)
+}
+
+func isShortDeckWheel(hand []poker.Card) bool {
+	ranks := lowRanks(hand)
+	wheel := []int32{9, 8, 7, 6, 1}
+	for i := range wheel {
+		if ranks[i] != wheel[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isFlush(hand []poker.Card) bool {
+	for _, card := range hand[1:] {
+		if card.Suit() != hand[0].Suit() {
+			return false
+		}
+	}
+	return true
+}
+
+// deuce to seven is the high hand ranking turned upside down, 7-5-4-3-2 is the best hand, aces are
+// always high so A-5-4-3-2 isn't a straight, it's the weakest ace high
+func evaluateDeuceToSeven(holeCards []poker.Card, board []poker.Card) int32 {
+	hand := holeCards
+	aceHighWheel := isWheel(hand)
+	if aceHighWheel {
+		// A-6-4-3-2 is the next worst ace high, the wheel slots in just below it
+		hand = make([]poker.Card, len(holeCards))
+		for i, card := range holeCards {
+			if card.Rank() == poker.NewCard("5s").Rank() {
+				card = poker.NewCard("6" + card.String()[1:])
+			}
+			hand[i] = card
+		}
+	}
+	rank := 2 * (7463 - poker.Evaluate(hand))
+	if aceHighWheel {
+		rank--
+	}
+	return rank
+}
+
+func describeDeuceToSeven(rank int32) string {
+	return poker.RankString(7463 - (rank+1)/2)
+}
+
+func isWheel(hand []poker.Card) bool {
+	ranks := lowRanks(hand)
+	for i, rank := range []int32{5, 4, 3, 2, 1} {
+		if ranks[i] != rank {
+			return false
+		}
+	}
+	return true
+}
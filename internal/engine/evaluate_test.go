@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/chehsunliu/poker"
+)
+
+func cards(s ...string) []poker.Card {
+	result := make([]poker.Card, len(s))
+	for i, c := range s {
+		result[i] = poker.NewCard(c)
+	}
+	return result
+}
+
+func TestEvaluateOmaha(t *testing.T) {
+	board := cards("Ah", "Kh", "Qh", "2c", "7d")
+
+	// one heart in the hand isn't a flush in omaha, it has to be two
+	oneHeart := evaluateOmaha(cards("Jh", "2s", "3s", "4d"), board)
+	if poker.RankClass(oneHeart) == 4 {
+		t.Errorf("Expected no flush with one heart, got %v", poker.RankString(oneHeart))
+	}
+	twoHearts := evaluateOmaha(cards("Jh", "3h", "3s", "4d"), board)
+	if poker.RankClass(twoHearts) != 4 {
+		t.Errorf("Expected a flush with two hearts, got %v", poker.RankString(twoHearts))
+	}
+}
+
+func TestEvaluateOmahaLow(t *testing.T) {
+	board := cards("2c", "4d", "5h", "Kc", "Ks")
+
+	eightLow, ok := evaluateOmahaLow(cards("Ac", "8d", "Qh", "Qd"), board)
+	if !ok {
+		t.Fatalf("Expected 8-5-4-2-A to qualify")
+	}
+	wheel, ok := evaluateOmahaLow(cards("Ad", "3h", "Ts", "Jd"), board)
+	if !ok || wheel >= eightLow {
+		t.Errorf("Expected 5-4-3-2-A to beat 8-5-4-2-A")
+	}
+
+	// only one low card in the hand can't make a low, it takes two
+	if _, ok := evaluateOmahaLow(cards("Ac", "Jd", "Qh", "Qd"), board); ok {
+		t.Errorf("Expected no low with one low hole card")
+	}
+	if _, ok := evaluateOmahaLow(cards("9c", "Td", "Jh", "Qd"), board); ok {
+		t.Errorf("Expected no low without low hole cards")
+	}
+}
+
+func TestEvaluateShortDeck(t *testing.T) {
+	board := cards("9h", "9d", "7h", "6h", "Kc")
+
+	flush := evaluateShortDeck(cards("Ah", "2h"), board)
+	fullHouse := evaluateShortDeck(cards("7c", "7s"), board)
+	if flush >= fullHouse {
+		t.Errorf("Expected a flush to beat a full house, got %v and %v", describeShortDeck(flush), describeShortDeck(fullHouse))
+	}
+
+	wheel := evaluateShortDeck(cards("Ac", "8s"), cards("9c", "7d", "6s", "Kh", "Qd"))
+	if describeShortDeck(wheel) != "Straight" {
+		t.Errorf("Expected A-6-7-8-9 to be a straight, got %v", describeShortDeck(wheel))
+	}
+	tenHigh := evaluateShortDeck(cards("Tc", "8s"), cards("9c", "7d", "6s", "Kh", "Qd"))
+	if tenHigh >= wheel {
+		t.Errorf("Expected 6-T to beat A-6-7-8-9")
+	}
+	trips := evaluateShortDeck(cards("9c", "9s"), cards("9d", "7d", "2s", "Kh", "Qd"))
+	if wheel >= trips {
+		t.Errorf("Expected A-6-7-8-9 to beat three of a kind")
+	}
+}
+
+func TestShortDeck(t *testing.T) {
+	d := newShortDeck()
+	if d.remaining() != 36 {
+		t.Fatalf("Expected 36 cards in a short deck, got %d", d.remaining())
+	}
+	for _, card := range d.draw(36) {
+		if card.Rank() < poker.NewCard("6s").Rank() {
+			t.Errorf("Expected no cards below six, got %v", card)
+		}
+	}
+	if newStandardDeck().remaining() != 52 {
+		t.Errorf("Expected 52 cards in a standard deck")
+	}
+}
+
+func TestEvaluateRazz(t *testing.T) {
+	wheel := evaluateRazz(cards("Ac", "2d", "Kh"), cards("3s", "4c", "5d", "Ks"))
+	if describeRazz(wheel) != "5-4-3-2-A low" {
+		t.Errorf("Expected 5-4-3-2-A low, got %v", describeRazz(wheel))
+	}
+	// straights and flushes don't count against a razz hand
+	flush := evaluateRazz(cards("Ac", "2c", "Kh"), cards("3c", "4c", "5c", "Ks"))
+	if flush != wheel {
+		t.Errorf("Expected a suited wheel to be the same low, got %v", describeRazz(flush))
+	}
+	eightLow := evaluateRazz(cards("Ac", "2d", "8h"), cards("3s", "4c", "8d", "8s"))
+	if eightLow <= wheel {
+		t.Errorf("Expected 8-4-3-2-A to lose to the wheel")
+	}
+	pair := evaluateRazz(cards("Ac", "Ad", "2h"), cards("2s", "3c", "3d", "4s"))
+	if describeRazz(pair) != "Pair" || pair <= evaluateRazz(cards("Kc", "Qd", "Jh"), cards("Ts", "9c", "Kd", "Ks")) {
+		t.Errorf("Expected a pair to lose to any five different cards, got %v", describeRazz(pair))
+	}
+}
+
+func TestEvaluateDeuceToSeven(t *testing.T) {
+	number1 := evaluateDeuceToSeven(cards("7c", "5d", "4h", "3s", "2c"), nil)
+	eightLow := evaluateDeuceToSeven(cards("8c", "5d", "4h", "3s", "2c"), nil)
+	if number1 >= eightLow {
+		t.Errorf("Expected 7-5-4-3-2 to beat 8-5-4-3-2")
+	}
+	// straights and flushes count against you
+	straight := evaluateDeuceToSeven(cards("6c", "5d", "4h", "3s", "2c"), nil)
+	flush := evaluateDeuceToSeven(cards("7c", "5c", "4c", "3c", "2c"), nil)
+	pair := evaluateDeuceToSeven(cards("2d", "5d", "4h", "3s", "2c"), nil)
+	if straight <= pair || flush <= pair {
+		t.Errorf("Expected a straight and a flush to lose to a pair")
+	}
+	// aces are high, A-5-4-3-2 is the worst ace high and better than any pair
+	wheel := evaluateDeuceToSeven(cards("Ac", "5d", "4h", "3s", "2c"), nil)
+	aceSix := evaluateDeuceToSeven(cards("Ac", "6d", "4h", "3s", "2c"), nil)
+	kingHigh := evaluateDeuceToSeven(cards("Kc", "Qd", "Jh", "Ts", "8c"), nil)
+	if !(kingHigh < wheel && wheel < aceSix && aceSix < pair) {
+		t.Errorf("Expected K-Q-J-T-8 < A-5-4-3-2 < A-6-4-3-2 < pair, got %v %v %v %v", kingHigh, wheel, aceSix, pair)
+	}
+	if describeDeuceToSeven(wheel) != "High Card" {
+		t.Errorf("Expected A-5-4-3-2 to be a high card hand, got %v", describeDeuceToSeven(wheel))
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/wegman7/game-engine/config"
+)
+
+// FuzzBetting reads a table and a run of betting actions out of the input and plays them on a
+// real engine. The first byte picks the number of seats and the betting structure, one byte per
+// seat is its stack, tiny stacks included, and every two bytes after that are an action and its
+// size. An action can come from the wrong player or be the wrong size, the engine has to turn
+// it down and carry on, or be a pre action queued for later. Once the input runs out everyone
+// checks or calls the hand down.
+//
+// Every hand has to keep its chips, never pay a player more than the side pot they're eligible
+// for and finish without a panic or getting stuck. go test -fuzz saves any input that breaks
+// one of these under testdata/fuzz so it replays as a regular test from then on.
+func FuzzBetting(f *testing.F) {
+	f.Add([]byte{0x00, 100, 100, 0, 0, 1, 0, 2, 0})
+	f.Add([]byte{0x07, 1, 3, 200, 50, 3, 9, 2, 0, 3, 200, 2, 0, 2, 0})
+	f.Add([]byte{0x13, 2, 2, 2, 200, 3, 4, 2, 0, 2, 0, 2, 0})
+	f.Add([]byte{0x26, 10, 20, 30, 40, 50, 60, 70, 80, 3, 0, 3, 1, 3, 2, 3, 3, 3, 4, 2, 0, 2, 0, 2, 0})
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if len(input) == 0 {
+			return
+		}
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+		config.AppConfig.MAX_PLAYERS = 9
+
+		seats := 2 + int(input[0]&0x07)
+		structure := []string{noLimit, potLimit, fixedLimit}[int(input[0]>>3)%3]
+		input = input[1:]
+		e := createEngine(nil, StartGameRequest{SmallBlind: 1, BigBlind: 2, Structure: structure})
+		clock := newFakeClock()
+		e.state.clock = clock
+		users := make([]string, seats)
+		for seat := range users {
+			stack := 100.0
+			if len(input) > 0 {
+				stack = float64(input[0]) + 1
+				input = input[1:]
+			}
+			users[seat] = string(rune('a' + seat))
+			e.queueEvent(Event{EngineCommand: "join", User: users[seat], SeatId: seat, Chips: stack})
+		}
+		e.queueEvent(Event{EngineCommand: "startGame"})
+		e.tick()
+		total := e.state.totalChips()
+
+		started := make(map[string]float64)
+		contributed := make(map[string]float64)
+		for hands, ticks := 0, 0; hands < 20 && e.engineState != StateProcessSitCommands; ticks++ {
+			if ticks > 1000 {
+				t.Fatalf("Stuck in %v", e.engineState)
+			}
+			switch e.engineState {
+			case StateStartHand:
+				for user, p := range e.state.players {
+					started[user] = p.chips
+					contributed[user] = 0
+				}
+			case StatePauseAfterEndHand:
+				checkHand(t, e.state, total, started, contributed)
+				hands++
+				ticks = 0
+			case StateProcessGameCommands:
+				if len(e.gameCommands) == 0 {
+					// a hand can go on as long as there's input to play, it's stuck if it doesn't end after that
+					if len(input) >= 2 {
+						ticks = 0
+					}
+					input = queueFuzzAction(e, users, input)
+				}
+			}
+
+			e.tick()
+			clock.advance(simulatedTick)
+			if err := e.state.checkInvariants(e.engineState); err != nil {
+				t.Fatalf("Invariant broken in %v: %v", e.engineState, err)
+			}
+			for user, p := range e.state.players {
+				contributed[user] = max(contributed[user], p.chipsInHand)
+			}
+		}
+	})
+}
+
+// queueFuzzAction turns the next two bytes into an action, without them the player in the
+// spotlight checks or calls
+func queueFuzzAction(e *engine, users []string, input []byte) []byte {
+	s := e.state
+	if len(input) < 2 {
+		command := "check"
+		if s.spotlight.chipsInPot < s.currentBet {
+			command = "call"
+		}
+		e.queueEvent(Event{EngineCommand: command, User: s.spotlight.user})
+		return nil
+	}
+
+	action, size := input[0], input[1]
+	event := Event{EngineCommand: []string{"fold", "check", "call", "bet"}[action&0x03], User: s.spotlight.user}
+	// now and then someone else tries to act
+	if action&0x80 != 0 {
+		event.User = users[int(action>>2)%len(users)]
+	}
+	// and sometimes picks a pre action instead
+	if action&0xc0 == 0xc0 {
+		event = Event{EngineCommand: "preAction", User: event.User, PreAction: []string{"", "checkFold", "check", "call", "callAny"}[size%5]}
+		e.queueEvent(event)
+		return input[2:]
+	}
+	if event.EngineCommand == "bet" {
+		p := s.spotlight
+		switch {
+		case size == 0:
+			event.Chips = p.chipsInPot + p.chips
+		case size < 128:
+			event.Chips = s.currentBet + s.minRaise*float64(size)/8
+		default:
+			event.Chips = float64(size - 128)
+		}
+	}
+	e.queueEvent(event)
+	return input[2:]
+}
+
+// checkHand makes sure the hand kept its chips and nobody won more than they could, a player
+// can't win more from anyone than the smaller of what the two of them put in
+func checkHand(t *testing.T, s *state, total float64, started map[string]float64, contributed map[string]float64) {
+	t.Helper()
+	if got := s.totalChips(); math.Abs(got-total) > 0.001 {
+		t.Fatalf("Expected %v chips at the table after the hand, got %v", total, got)
+	}
+	for user, p := range s.players {
+		won := p.chips - started[user] + contributed[user]
+		eligible := 0.0
+		for other := range s.players {
+			eligible += min(contributed[user], contributed[other])
+		}
+		if won > eligible+0.001 {
+			t.Fatalf("Expected %v to win at most %v, got %v", user, eligible, won)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"log"
+
+	"github.com/chehsunliu/poker"
+)
+
+// HandAction is one time chips moved in the hand, Amount is how many, for a bet it's what the
+// player raised to. Showing cards is an action too, Cards is what was turned over.
+type HandAction struct {
+	Street string       `json:"street"`
+	User   string       `json:"user"`
+	Action string       `json:"action"`
+	Amount float64      `json:"amount"`
+	Cards  []poker.Card `json:"cards,omitempty"`
+}
+
+var streetNames = map[street]string{
+	BetweenHands:  "betweenHands",
+	Preflop:       "preflop",
+	Flop:          "flop",
+	Turn:          "turn",
+	River:         "river",
+	Predraw:       "predraw",
+	FirstDraw:     "firstDraw",
+	SecondDraw:    "secondDraw",
+	ThirdDraw:     "thirdDraw",
+	ThirdStreet:   "thirdStreet",
+	FourthStreet:  "fourthStreet",
+	FifthStreet:   "fifthStreet",
+	SixthStreet:   "sixthStreet",
+	SeventhStreet: "seventhStreet",
+}
+
+func (st street) String() string {
+	return streetNames[st]
+}
+
+// record adds an action to the hand history, the history starts over every hand
+func (s *state) record(user string, action string, amount float64) {
+	s.history = append(s.history, HandAction{Street: s.street.String(), User: user, Action: action, Amount: amount})
+}
+
+// recordCards adds cards a player showed to the hand history, nobody else's cards ever go in it
+func (s *state) recordCards(user string, action string, cards []poker.Card) {
+	s.history = append(s.history, HandAction{Street: s.street.String(), User: user, Action: action, Cards: cards})
+}
+
+// returnUncalledBet gives the biggest bet of the street back down to the most anyone else put
+// in, nobody called the rest of it so it never makes it into a pot
+func (s *state) returnUncalledBet() {
+	var top *player
+	called := 0.0
+	for _, p := range s.players {
+		if top == nil || p.chipsInPot > top.chipsInPot {
+			if top != nil {
+				called = max(called, top.chipsInPot)
+			}
+			top = p
+		} else {
+			called = max(called, p.chipsInPot)
+		}
+	}
+	if top == nil {
+		return
+	}
+	uncalled := top.chipsInPot - called
+	if uncalled <= 0.001 {
+		return
+	}
+	log.Println("Uncalled bet of", uncalled, "returned to", top.user)
+	top.chips += uncalled
+	top.chipsInPot -= uncalled
+	top.chipsInHand -= uncalled
+	s.pot -= uncalled
+	s.currentBet = called
+	s.record(top.user, "uncalledBet", uncalled)
+}
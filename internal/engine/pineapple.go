@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/chehsunliu/poker"
+	"github.com/wegman7/game-engine/config"
+)
+
+// pineapple games deal an extra hole card and everyone still in the hand throws one away once
+// betting on the variant's discard street is over, all at the same time rather than in turn
+
+func (s *state) isDiscardDue() bool {
+	if s.variant.discardOn == BetweenHands || s.street != s.variant.discardOn {
+		return false
+	}
+	return !s.everyoneDiscarded()
+}
+
+func (s *state) startDiscard() {
+	s.spotlight = nil
+	s.discardDeadline = s.clock.now().Add(config.AppConfig.DISCARD_TIMEOUT)
+}
+
+func (s *state) everyoneDiscarded() bool {
+	pointer := s.psuedoDealer
+	for {
+		if len(pointer.holeCards) == s.variant.holeCards {
+			return false
+		}
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			return true
+		}
+	}
+}
+
+// hasDiscarded is true once the hand is past the discard, during the discard some players have
+// and some haven't
+func (s *state) hasDiscarded(engineState engineState) bool {
+	if s.variant.discardOn == BetweenHands {
+		return false
+	}
+	discardIndex := 0
+	for i, st := range s.variant.streets {
+		if st == s.variant.discardOn {
+			discardIndex = i
+		}
+	}
+	if s.streetIndex() != discardIndex {
+		return s.streetIndex() > discardIndex
+	}
+	return engineState == StateRunItVote
+}
+
+func (s *state) discardOne(p *player, discards []poker.Card) error {
+	if p.nextInHand == nil {
+		return errors.New("player is not in the hand")
+	}
+	if len(p.holeCards) < s.variant.holeCards {
+		return errors.New("player has already discarded")
+	}
+	if len(discards) != 1 {
+		return errors.New("discard exactly one card")
+	}
+	if err := p.verifyLegalDiscard(discards); err != nil {
+		return err
+	}
+	s.muckCard(p, discards[0])
+	return nil
+}
+
+// anyone who runs out of time throws away the last card they were dealt
+func (s *state) discardForRemaining() {
+	pointer := s.psuedoDealer
+	for {
+		if len(pointer.holeCards) == s.variant.holeCards {
+			s.muckCard(pointer, pointer.holeCards[len(pointer.holeCards)-1])
+		}
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			return
+		}
+	}
+}
+
+func (s *state) muckCard(p *player, card poker.Card) {
+	kept := make([]poker.Card, 0, len(p.holeCards)-1)
+	for _, c := range p.holeCards {
+		if c != card {
+			kept = append(kept, c)
+		}
+	}
+	p.holeCards = kept
+	s.discards = append(s.discards, card)
+}
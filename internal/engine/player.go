@@ -2,9 +2,11 @@ package engine
 
 import (
 	"errors"
+	"fmt"
 	"log"
 
 	"github.com/chehsunliu/poker"
+	"github.com/wegman7/game-engine/config"
 )
 
 type player struct {
@@ -13,9 +15,25 @@ type player struct {
 	sittingOut      bool
 	chips           float64
 	chipsInPot      float64
-	maxWin		  	float64
+	chipsInHand     float64
+	pendingChips    float64
+	runItVote       int
+	// acted is set once the player checks, calls or bets on the street, actedOn is the bet they
+	// acted on last
+	acted           bool
+	actedOn         float64
+	// preAction is what the player wants to do once it's their turn, preActionBet is the bet it
+	// was chosen against
+	preAction       string
+	preActionBet    float64
 	timeBank        float64
 	holeCards       []poker.Card
+	upCards         []poker.Card
+	// shownCards are the hole cards everyone has seen
+	shownCards      []poker.Card
+	bot             bot
+	// botName is which bot plays the seat, it's what a snapshot brings it back with
+	botName         string
 	commandHandlers map[string]commandHandler
 	nextInHand      *player
 	next            *player
@@ -30,7 +48,8 @@ func createPlayer(event Event) *player {
 		sittingOut:   false,
 		chips:        event.Chips,
 		chipsInPot:   0.0,
-		maxWin:       0.0,
+		chipsInHand:  0.0,
+		pendingChips: 0.0,
 		timeBank:     0,
 		holeCards:    nil,
 		nextInHand:   nil,
@@ -45,6 +64,10 @@ func createPlayer(event Event) *player {
 	p.commandHandlers["check"] = p.check
 	p.commandHandlers["call"] = p.call
 	p.commandHandlers["bet"] = p.bet
+	p.commandHandlers["runIt"] = p.runIt
+	p.commandHandlers["discard"] = p.discard
+	p.commandHandlers["show"] = p.show
+	p.commandHandlers["preAction"] = p.setPreAction
 
 	return &p
 }
@@ -56,20 +79,35 @@ func (p *player) copy() *player {
         sittingOut:  p.sittingOut,
         chips:       p.chips,
         chipsInPot:  p.chipsInPot,
-        maxWin:      p.maxWin,
         timeBank:    p.timeBank,
         holeCards:   append([]poker.Card{}, p.holeCards...),
+        upCards:     append([]poker.Card{}, p.upCards...),
+        shownCards:  append([]poker.Card{}, p.shownCards...),
+        preAction:   p.preAction,
     }
 }
 
 
 func (p *player) makeAction(event *Event, e *engine, s *state) error {
-	err := p.commandHandlers[event.EngineCommand](event, e, s)
+	handler, ok := p.commandHandlers[event.EngineCommand]
+	if !ok {
+		return errors.New("unknown command")
+	}
+	err := handler(event, e, s)
 	return err
 }
 
-// Add chips to the player's total
+// Add chips to the player's total, chips added during a hand are held until the hand ends
 func (p *player) addChips(event *Event, e *engine, s *state) error {
+	if err := s.verifyTopUp(p, event.Chips); err != nil {
+		return err
+	}
+
+	if s.street != BetweenHands {
+		log.Println("Deferring chips for player until the hand ends: ", p.user, "-", event.Chips)
+		p.pendingChips += event.Chips
+		return nil
+	}
 	log.Println("Adding chips to player: ", p.user, "-", event.Chips)
 	p.chips = p.chips + event.Chips
 	return nil
@@ -95,6 +133,8 @@ func (p *player) fold(event *Event, e *engine, s *state) error {
 	nextPlayer := p.nextInHand
 	wasLastAggressor := s.lastAggressor == p
 	s.removePlayerInHand(p)
+	s.folded = append(s.folded, p)
+	s.record(p.user, "fold", 0)
 	if s.isEveryoneFolded() {
 		e.transitionState(StatePauseAfterEveryoneFolded)
 		return nil
@@ -125,7 +165,12 @@ func (p *player) check(event *Event, e *engine, s *state) error {
 	if err := p.verifySpotlight(s); err != nil {
 		return err
 	}
+	if err := p.verifyLegalCheck(s); err != nil {
+		return err
+	}
 
+	p.actOn(s)
+	s.record(p.user, "check", 0)
 	s.rotateSpotlight()
 	if s.isStreetComplete() {
 		e.transitionState(StateEndStreet)
@@ -145,6 +190,8 @@ func (p *player) call(event *Event, e *engine, s *state) error {
 	amount := min(s.currentBet - p.chipsInPot, p.chips)
 	p.putChipsInPot(s, amount)
 
+	p.actOn(s)
+	s.record(p.user, "call", amount)
 	s.rotateSpotlight()
 	if s.isStreetComplete() {
 		e.transitionState(StateEndStreet)
@@ -161,16 +208,26 @@ func (p *player) bet(event *Event, e *engine, s *state) error {
 	
 	// betAmount is the amount the player is actually putting in the pot
 	betAmount := min(event.Chips - p.chipsInPot, p.chips)
+	// raising to their whole stack is all in even if taking off what's already in front of them isn't exact
+	if p.chips-betAmount < 0.001 {
+		betAmount = p.chips
+	}
 	if err := p.verifyLegalBet(s, betAmount); err != nil {
 		return err
 	}
 
 	p.putChipsInPot(s, betAmount)
+	s.bets++
 
 	// we need to wrap this in a max function because a player could be going all in for a small amount
 	s.minRaise = max(p.chipsInPot - s.currentBet, s.minRaise)
 	s.lastAggressor = p
+	s.aggressor = p
+	s.aggressorStreet = s.street
 	s.currentBet = p.chipsInPot
+	s.invalidatePreActions()
+	p.actOn(s)
+	s.record(p.user, "bet", p.chipsInPot)
 	s.rotateSpotlight()
 	if s.isStreetComplete() {
 		e.transitionState(StateEndStreet)
@@ -179,12 +236,138 @@ func (p *player) bet(event *Event, e *engine, s *state) error {
 	return nil
 }
 
+// runIt votes for how many times to run out the rest of the board
+func (p *player) runIt(event *Event, e *engine, s *state) error {
+	if e.engineState != StateRunItVote {
+		return errors.New("there is no run it vote in progress")
+	}
+	if p.nextInHand == nil {
+		return errors.New("player is not in the hand")
+	}
+	if event.Runs < 1 || event.Runs > config.AppConfig.MAX_RUNS {
+		return fmt.Errorf("can only run it between 1 and %d times", config.AppConfig.MAX_RUNS)
+	}
+
+	p.runItVote = event.Runs
+	s.prevState = nil
+	return nil
+}
+
+// discard swaps any of the player's cards for new ones in a draw game, discarding nothing stands
+// pat, in pineapple it throws away the extra card
+func (p *player) discard(event *Event, e *engine, s *state) error {
+	if e.engineState != StateDraw && e.engineState != StateDiscard {
+		return errors.New("there is nothing to discard right now")
+	}
+	discards, err := parseCards(event.Cards)
+	if err != nil {
+		return err
+	}
+	if e.engineState == StateDiscard {
+		return s.discardOne(p, discards)
+	}
+	if err := p.verifySpotlight(s); err != nil {
+		return err
+	}
+	if err := p.verifyLegalDiscard(discards); err != nil {
+		return err
+	}
+
+	s.replaceCards(p, discards)
+	s.spotlight = s.spotlight.nextInHand
+	if s.spotlight == s.lastAggressor {
+		e.transitionState(StateDealStreet)
+	}
+	return nil
+}
+
+// setPreAction picks what to do before it's the player's turn, it's played for them as soon as
+// the action gets to them, an empty pre action takes it back
+func (p *player) setPreAction(event *Event, e *engine, s *state) error {
+	if s.street == BetweenHands || isBettingOver(e.engineState) {
+		return errors.New("there is no betting to act on")
+	}
+	if p.nextInHand == nil {
+		return errors.New("player is not in the hand")
+	}
+	if p.isAllIn() {
+		return errors.New("player is all in")
+	}
+	switch event.PreAction {
+	case "", "checkFold", "callAny":
+	case "check":
+		if err := p.verifyLegalCheck(s); err != nil {
+			return err
+		}
+	case "call":
+		if err := p.verifyLegalCall(s); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unknown pre action")
+	}
+
+	p.preAction = event.PreAction
+	p.preActionBet = s.currentBet
+	return nil
+}
+
+// show turns some or all of the player's hole cards face up once the betting is over, a player
+// who won without a showdown or mucked at one can still show what they had
+func (p *player) show(event *Event, e *engine, s *state) error {
+	if !isBettingOver(e.engineState) {
+		return errors.New("you can only show your cards once the betting is over")
+	}
+	if p.nextInHand == nil {
+		return errors.New("player is not in the hand")
+	}
+	cards, err := parseCards(event.Cards)
+	if err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		cards = p.holeCards
+	}
+	for _, card := range cards {
+		if !containsCard(p.holeCards, card) {
+			return errors.New("can only show cards in your hand")
+		}
+	}
+
+	s.showCards(p, cards)
+	return nil
+}
+
 func (p *player) putChipsInPot(s *state, amount float64) {
 	s.pot += amount
 	p.chipsInPot += amount
+	p.chipsInHand += amount
 	p.chips -= amount
 }
 
+// handBoard is every card the player makes a hand with besides their hole cards, their stud
+// upcards and the board
+func (p *player) handBoard(board []poker.Card) []poker.Card {
+	if len(p.upCards) == 0 {
+		return board
+	}
+	cards := make([]poker.Card, 0, len(p.upCards)+len(board))
+	cards = append(cards, p.upCards...)
+	return append(cards, board...)
+}
+
+func (p *player) actOn(s *state) {
+	p.acted = true
+	p.actedOn = s.currentBet
+}
+
+// canRaise is true until the player acts on the street and again once they face at least a full
+// raise since then, an all in for less than a full raise lets them call or fold but doesn't reopen
+// the betting
+func (p *player) canRaise(s *state) bool {
+	return !p.acted || s.currentBet-p.actedOn >= s.minRaise-0.001
+}
+
 func (p *player) isAllIn() bool {
 	return p.chips == 0
 }
@@ -196,6 +379,14 @@ func (p *player) verifySpotlight(s *state) error {
 	return nil
 }
 
+func (p *player) verifyLegalCheck(s *state) error {
+	if p.chipsInPot < s.currentBet {
+		return errors.New("player has to call or fold")
+	}
+
+	return nil
+}
+
 func (p *player) verifyLegalCall(s *state) error {
 	if p.chipsInPot == s.currentBet {
 		return errors.New("player has already matched the bet")
@@ -205,16 +396,20 @@ func (p *player) verifyLegalCall(s *state) error {
 }
 
 func (p *player) verifyLegalBet(s *state, betAmount float64) error {
-	// if betAmount == p.chips then the player is all in and the bet is legal
-	if betAmount < s.minRaise + s.currentBet && betAmount > p.chips + p.chipsInPot {
-		return errors.New("bet amount is less than minimum")
+	// betAmount is what goes in now, raiseTo is the player's bet for the street once it's in
+	raiseTo := p.chipsInPot + betAmount
+	if raiseTo <= s.currentBet {
+		return errors.New("bet amount must be more than the current bet")
 	}
-
-	return nil
+	if !p.canRaise(s) {
+		return errors.New("the betting wasn't reopened, you can only call or fold")
+	}
+	// if betAmount == p.chips then the player is all in and any amount is legal
+	return s.verifyBetSize(p, raiseTo, betAmount == p.chips)
 }
 
 func comparePlayers(prev *player, curr *player) bool {
-	if !CompareCardSlices(prev.holeCards, curr.holeCards) {
+	if !CompareCardSlices(prev.holeCards, curr.holeCards) || !CompareCardSlices(prev.upCards, curr.upCards) || !CompareCardSlices(prev.shownCards, curr.shownCards) {
 		return false
 	}
 
@@ -222,5 +417,7 @@ func comparePlayers(prev *player, curr *player) bool {
 	return prev.sittingOut == curr.sittingOut &&
 		prev.chips == curr.chips &&
 		prev.chipsInPot == curr.chipsInPot &&
+		prev.runItVote == curr.runItVote &&
+		prev.preAction == curr.preAction &&
 		prev.timeBank == curr.timeBank
 }
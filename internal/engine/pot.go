@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"log"
+	"sort"
+)
+
+// pot is chips that only the players in eligible can win, the main pot comes first and every
+// side pot after it has fewer players in it
+type pot struct {
+	amount   float64
+	eligible []*player
+}
+
+type SerializePot struct {
+	Amount   float64  `json:"amount"`
+	Eligible []string `json:"eligible"`
+}
+
+// buildPots splits everything put in so far into a main pot and side pots, a new pot starts
+// above every all in player's stake and each one is for the players in the hand who put in at
+// least that much. What's put in above everyone still in the hand only came from players who
+// folded, it goes to the last of them to fold since nobody was left to take it from them.
+func (s *state) buildPots() {
+	inHand := s.playersInHand()
+	levels := make([]float64, 0)
+	top := 0.0
+	for _, p := range s.players {
+		top = max(top, p.chipsInHand)
+	}
+	for _, p := range inHand {
+		if p.isAllIn() && p.chipsInHand < top {
+			levels = append(levels, p.chipsInHand)
+		}
+	}
+	levels = append(levels, top)
+	sort.Float64s(levels)
+
+	s.pots = make([]pot, 0, len(levels))
+	floor := 0.0
+	for _, level := range levels {
+		if level-floor <= 0.001 {
+			continue
+		}
+		sidePot := pot{}
+		for _, p := range s.players {
+			sidePot.amount += min(max(p.chipsInHand-floor, 0), level-floor)
+		}
+		for _, p := range inHand {
+			if p.chipsInHand >= level-0.001 {
+				sidePot.eligible = append(sidePot.eligible, p)
+			}
+		}
+		if len(sidePot.eligible) == 0 {
+			sidePot.eligible = s.lastToFoldAbove(floor)
+		}
+		s.pots = append(s.pots, sidePot)
+		floor = level
+	}
+}
+
+// playersInHand is everyone still in the hand, starting from the psuedo dealer
+func (s *state) playersInHand() []*player {
+	players := make([]*player, 0)
+	if s.psuedoDealer == nil {
+		return players
+	}
+	pointer := s.psuedoDealer
+	for {
+		players = append(players, pointer)
+		pointer = pointer.nextInHand
+		if pointer == nil || pointer == s.psuedoDealer {
+			return players
+		}
+	}
+}
+
+func (s *state) lastToFoldAbove(floor float64) []*player {
+	for i := len(s.folded) - 1; i >= 0; i-- {
+		if s.folded[i].chipsInHand > floor+0.001 {
+			return []*player{s.folded[i]}
+		}
+	}
+	return nil
+}
+
+// payPot splits a share of a pot between its winners
+func (s *state) payPot(winners []*player, amount float64) {
+	for _, winner := range winners {
+		winner.chips += amount / float64(len(winners))
+		s.record(winner.user, "win", amount/float64(len(winners)))
+		log.Println(winner.user, " wins ", amount/float64(len(winners)))
+	}
+	s.pot -= amount
+	s.collectedPot -= amount
+}
+
+func (s *state) potsTotal() float64 {
+	total := 0.0
+	for _, p := range s.pots {
+		total += p.amount
+	}
+	return total
+}
+
+func createSerializePots(pots []pot) []SerializePot {
+	serializePots := make([]SerializePot, 0, len(pots))
+	for _, p := range pots {
+		eligible := make([]string, 0, len(p.eligible))
+		for _, player := range p.eligible {
+			eligible = append(eligible, player.user)
+		}
+		serializePots = append(serializePots, SerializePot{Amount: p.amount, Eligible: eligible})
+	}
+	return serializePots
+}
@@ -0,0 +1,63 @@
+package engine
+
+import "log"
+
+// applyPreActions plays the pre action of whoever the spotlight is on, and of the next player
+// and so on, until someone has to decide for themselves
+func (e *engine) applyPreActions() {
+	for e.engineState == StateProcessGameCommands {
+		p := e.state.spotlight
+		command := p.takePreAction(e.state)
+		if command == "" {
+			return
+		}
+		log.Println("Playing pre action for", p.user, "-", command)
+		if err := p.makeAction(&Event{EngineCommand: command, User: p.user}, e, e.state); err != nil {
+			log.Println("Error playing pre action: ", err)
+			e.sendError(p.user, err)
+			return
+		}
+	}
+}
+
+// takePreAction clears the player's pre action and says which command it comes to now
+func (p *player) takePreAction(s *state) string {
+	preAction := p.preAction
+	p.preAction = ""
+	facingBet := p.chipsInPot < s.currentBet
+	switch preAction {
+	case "checkFold":
+		if facingBet {
+			return "fold"
+		}
+		return "check"
+	case "check":
+		if !facingBet {
+			return "check"
+		}
+	case "call":
+		if facingBet && s.currentBet == p.preActionBet {
+			return "call"
+		}
+	case "callAny":
+		if facingBet {
+			return "call"
+		}
+		return "check"
+	}
+	return ""
+}
+
+// invalidatePreActions takes back the pre actions a new bet makes stale, a check can't be played
+// into a bet and a call was only for the bet it was chosen against, check/fold and call any still
+// stand
+func (s *state) invalidatePreActions() {
+	for _, p := range s.players {
+		switch {
+		case p.preAction == "check" && p.chipsInPot < s.currentBet,
+			p.preAction == "call" && p.preActionBet != s.currentBet:
+			log.Println("Bet changed, clearing pre action for", p.user, "-", p.preAction)
+			p.preAction = ""
+		}
+	}
+}
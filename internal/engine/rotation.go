@@ -0,0 +1,75 @@
+package engine
+
+// mixed game tables rotate through a list of games, each played for a number of hands or orbits,
+// the switch happens between hands so a hand is always played start to finish as one game
+
+type RotationGame struct {
+	Game      string `json:"game"`
+	Structure string `json:"structure"`
+}
+
+func verifyRotation(rotation []RotationGame) error {
+	for _, game := range rotation {
+		if _, err := lookupVariant(game.Game); err != nil {
+			return err
+		}
+		if err := verifyStructure(game.Structure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setGame switches the variant and betting structure, the structure defaults to the variant's own
+func (s *state) setGame(game string, structure string) {
+	v, err := lookupVariant(game)
+	if err != nil {
+		return
+	}
+	s.variant = v
+	s.structure = structure
+	if s.structure == "" {
+		s.structure = v.structure
+	}
+}
+
+// handsPerGame is either a fixed number of hands or enough orbits for everyone dealt into the
+// game's first hand to have the button that many times, one orbit if neither is set
+func (s *state) handsPerGame() int {
+	if s.rotateEvery > 0 {
+		return s.rotateEvery
+	}
+	return max(s.rotateOrbits, 1) * s.orbitSize
+}
+
+func (s *state) isRotationDue() bool {
+	return len(s.rotation) > 1 && s.gameHands >= s.handsPerGame()
+}
+
+func (s *state) rotateGame() {
+	s.rotationIndex = (s.rotationIndex + 1) % len(s.rotation)
+	next := s.rotation[s.rotationIndex]
+	s.setGame(next.Game, next.Structure)
+	s.gameHands = 0
+}
+
+// startGameHand counts the hand toward the current game, the first hand sets the orbit size
+func (s *state) startGameHand() {
+	s.gameHands++
+	if s.gameHands == 1 {
+		s.orbitSize = s.countPlayersInHand()
+	}
+}
+
+// nextGame is announced in state so players know what's coming and when
+func (s *state) nextGame() (RotationGame, int) {
+	if len(s.rotation) < 2 {
+		return RotationGame{}, 0
+	}
+	next := s.rotation[(s.rotationIndex+1)%len(s.rotation)]
+	if next.Structure == "" {
+		v, _ := lookupVariant(next.Game)
+		next.Structure = v.structure
+	}
+	return next, max(s.handsPerGame()-s.gameHands, 0)
+}
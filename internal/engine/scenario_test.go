@@ -0,0 +1,782 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chehsunliu/poker"
+	"github.com/wegman7/game-engine/config"
+)
+
+// scenario scripts one hand: who sits where with how much, the cards they're dealt, the board
+// and every action in order, then plays it on a real engine through queueEvent like a
+// websocket would and checks how it turned out
+//
+//	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+//		seat("alice", 1, 100).
+//		seat("bob", 5, 100).
+//		button("alice").
+//		deal("alice", "As Ad").
+//		deal("bob", "Ks Kd").
+//		board("2c 3c 7h 8h 9d").
+//		act("alice", "call").
+//		act("bob", "check").
+//		run().
+//		expectWinners("alice")
+type scenario struct {
+	t          *testing.T
+	table      StartGameRequest
+	joins      []Event
+	buttonOn   string
+	holeCards  map[string][]poker.Card
+	boardCards []poker.Card
+	steps      []step
+	e          *engine
+	stacks     map[string]float64
+	pot        float64
+}
+
+// step is one thing a player does in the hand, most wait for the player's turn and have to be
+// legal then
+type step struct {
+	Event
+	// anytime steps only wait until there's betting to act on or the betting is over
+	anytime bool
+	// refused steps go straight to the player's handler, which has to turn them down
+	refused bool
+	// check steps don't send anything, they say what's wrong with the player right then if anything
+	check func(p *player) string
+}
+
+// scripted hands never wait on the clock, timeouts only run out if the script waits for them
+const scenarioTimeout = time.Hour
+
+func newScenario(t *testing.T, table StartGameRequest) *scenario {
+	t.Helper()
+	return &scenario{
+		t:         t,
+		table:     table,
+		holeCards: make(map[string][]poker.Card),
+		stacks:    make(map[string]float64),
+	}
+}
+
+func (sc *scenario) seat(user string, seatId int, chips float64) *scenario {
+	sc.joins = append(sc.joins, Event{EngineCommand: "join", User: user, SeatId: seatId, Chips: chips})
+	sc.stacks[user] = chips
+	return sc
+}
+
+// button puts the dealer button on the player for the hand, otherwise it goes wherever the
+// table would put it first
+func (sc *scenario) button(user string) *scenario {
+	sc.buttonOn = user
+	return sc
+}
+
+// deal stacks the player's hole cards, anyone left out gets random ones
+func (sc *scenario) deal(user string, cards string) *scenario {
+	sc.holeCards[user] = sc.parse(cards)
+	return sc
+}
+
+// board is every card that comes off the deck after the hole cards, in order, with more than one
+// board each street is dealt to every board in turn
+func (sc *scenario) board(cards string) *scenario {
+	sc.boardCards = sc.parse(cards)
+	return sc
+}
+
+func (sc *scenario) act(user string, command string) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: command, User: user}})
+	return sc
+}
+
+// bet raises to chips like a bet from the client does
+func (sc *scenario) bet(user string, chips float64) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: "bet", User: user, Chips: chips}})
+	return sc
+}
+
+func (sc *scenario) runIt(user string, runs int) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: "runIt", User: user, Runs: runs}})
+	return sc
+}
+
+func (sc *scenario) discard(user string, cards string) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: "discard", User: user, Cards: strings.Fields(cards)}})
+	return sc
+}
+
+// show turns over the cards once the betting is over, no cards shows them all
+func (sc *scenario) show(user string, cards string) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: "show", User: user, Cards: strings.Fields(cards)}})
+	return sc
+}
+
+// preAct picks what the player does once it's their turn, it's sent as soon as there's betting
+// and never waits for their turn
+func (sc *scenario) preAct(user string, preAction string) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: "preAction", User: user, PreAction: preAction}, anytime: true})
+	return sc
+}
+
+// topUp adds chips once there's betting, they're held until the hand ends
+func (sc *scenario) topUp(user string, chips float64) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{EngineCommand: "addChips", User: user, Chips: chips}, anytime: true})
+	return sc
+}
+
+// cantRaise is a step that doesn't act, it checks the player can call or fold but not raise
+// when it's their turn, not even all in
+func (sc *scenario) cantRaise(user string) *scenario {
+	sc.steps = append(sc.steps, step{Event: Event{User: user}})
+	return sc
+}
+
+// expectPreAction is a step that checks the pre action the player sees in their view
+func (sc *scenario) expectPreAction(user string, preAction string) *scenario {
+	check := func(p *player) string {
+		if got := createPlayerView(sc.e.state, p).PreAction; got != preAction {
+			return fmt.Sprintf("expected %v's view to have pre action %q, got %q", user, preAction, got)
+		}
+		return ""
+	}
+	sc.steps = append(sc.steps, step{Event: Event{User: user}, anytime: true, check: check})
+	return sc
+}
+
+// refused is a step the engine has to turn down whenever it comes, show, preAction and the
+// like are sent with their cards or pre action
+func (sc *scenario) refused(user string, command string, event Event) *scenario {
+	event.EngineCommand, event.User = command, user
+	sc.steps = append(sc.steps, step{Event: event, anytime: true, refused: true})
+	return sc
+}
+
+func (sc *scenario) parse(cards string) []poker.Card {
+	sc.t.Helper()
+	parsed, err := parseCards(strings.Fields(cards))
+	if err != nil {
+		sc.t.Fatalf("Bad cards %q: %v", cards, err)
+	}
+	return parsed
+}
+
+// run plays the hand, every step has to be something its player can do when it comes up
+func (sc *scenario) run() *scenario {
+	sc.t.Helper()
+	timeouts := []*time.Duration{&config.AppConfig.RUN_IT_TIMEOUT, &config.AppConfig.DISCARD_TIMEOUT, &config.AppConfig.CHOOSE_GAME_TIMEOUT}
+	saved := make([]time.Duration, len(timeouts))
+	for i, timeout := range timeouts {
+		saved[i], *timeout = *timeout, scenarioTimeout
+	}
+	defer func() {
+		for i, timeout := range timeouts {
+			*timeout = saved[i]
+		}
+	}()
+	config.AppConfig.MAX_PLAYERS = 9
+	config.AppConfig.MAX_RUNS = 3
+
+	sc.e = createEngine(nil, sc.table)
+	sc.e.state.clock = newFakeClock()
+	for _, join := range sc.joins {
+		sc.e.queueEvent(join)
+	}
+	sc.e.queueEvent(Event{EngineCommand: "startGame", User: sc.table.Owner})
+	sc.tickUntil(func() bool { return sc.e.engineState == StateStartHand }, "the hand to start")
+	if sc.buttonOn != "" {
+		sc.placeButton()
+	}
+	sc.tickUntil(func() bool { return sc.e.engineState == StateDealCards }, "the deal")
+	sc.stackDeck()
+
+	for i, step := range sc.steps {
+		p := sc.e.state.players[step.User]
+		if p == nil {
+			sc.t.Fatalf("Step %d: %v isn't at the table", i+1, step.User)
+		}
+		sc.tickUntil(func() bool {
+			legal := sc.e.state.legalActions(p, sc.e.engineState)
+			if step.anytime {
+				return sc.e.engineState == StateProcessGameCommands || isBettingOver(sc.e.engineState) || sc.handOver()
+			}
+			return legal.any() || (step.EngineCommand == "show" && legal.Show) || sc.handOver()
+		}, step.User+" to act")
+		if step.check != nil {
+			if problem := step.check(p); problem != "" {
+				sc.t.Fatalf("Step %d: %v", i+1, problem)
+			}
+			continue
+		}
+		if step.refused {
+			if err := p.makeAction(&step.Event, sc.e, sc.e.state); err == nil {
+				sc.t.Fatalf("Step %d: expected the engine to turn down %v's %v in %v", i+1, step.User, step.EngineCommand, sc.e.engineState)
+			}
+			continue
+		}
+		if step.EngineCommand == "" {
+			if legal := sc.e.state.legalActions(p, sc.e.engineState); legal.Bet || !legal.Call {
+				sc.t.Fatalf("Step %d: expected %v to call or fold but not raise, they can %+v", i+1, step.User, legal)
+			}
+			if err := p.verifyLegalBet(sc.e.state, p.chips); err == nil {
+				sc.t.Fatalf("Step %d: expected the engine to turn down a raise from %v", i+1, step.User)
+			}
+			continue
+		}
+		if !sc.isLegal(p, step) {
+			sc.t.Fatalf("Step %d: %v can't %v %v in %v, they can %+v", i+1, step.User, step.EngineCommand, step.Chips, sc.e.engineState, sc.e.state.legalActions(p, sc.e.engineState))
+		}
+		chips, pending := p.chips, p.pendingChips
+		sc.e.queueEvent(step.Event)
+		sc.e.tick()
+		sc.checkInvariants()
+		if step.EngineCommand == "addChips" && (p.chips != chips || p.pendingChips != pending+step.Chips) {
+			sc.t.Fatalf("Step %d: expected %v's top-up held until the hand ends, got %v with %v pending", i+1, step.User, p.chips, p.pendingChips)
+		}
+	}
+	sc.tickUntil(sc.handOver, "the hand to end")
+	return sc
+}
+
+func (sc *scenario) tickUntil(done func() bool, what string) {
+	sc.t.Helper()
+	for i := 0; i < 100; i++ {
+		if done() {
+			return
+		}
+		if sc.e.engineState == StateShowdown || sc.e.engineState == StateEveryoneFoldedPayout {
+			sc.pot = max(sc.pot, sc.e.state.pot)
+		}
+		sc.e.tick()
+		sc.checkInvariants()
+	}
+	sc.t.Fatalf("Expected %v, got stuck in %v", what, sc.e.engineState)
+}
+
+func (sc *scenario) checkInvariants() {
+	sc.t.Helper()
+	if err := sc.e.state.checkInvariants(sc.e.engineState); err != nil {
+		sc.t.Fatalf("Invariant broken in %v: %v", sc.e.engineState, err)
+	}
+	// the only hole cards anyone else sees are the ones the history says were shown
+	for _, public := range createSerializeState(sc.e.state, false, sc.e.engineState).Players {
+		shown := make([]poker.Card, 0)
+		for _, a := range sc.e.state.history {
+			if a.User == public.User && a.Action == "show" {
+				shown = append(shown, a.Cards...)
+			}
+		}
+		for _, card := range public.HoleCards {
+			if !containsCard(shown, card) {
+				sc.t.Fatalf("Expected only shown cards in the state in %v, got %v for %v", sc.e.engineState, public.HoleCards, public.User)
+			}
+		}
+	}
+}
+
+func (sc *scenario) handOver() bool {
+	return sc.e.engineState == StatePauseAfterEndHand
+}
+
+// the table rotates the button before every hand, so it starts one seat to the right
+func (sc *scenario) placeButton() {
+	sc.t.Helper()
+	button, ok := sc.e.state.players[sc.buttonOn]
+	if !ok {
+		sc.t.Fatalf("Can't put the button on %v, they aren't at the table", sc.buttonOn)
+	}
+	pointer := button
+	for pointer.next != button {
+		pointer = pointer.next
+	}
+	sc.e.state.dealer = pointer
+}
+
+// stackDeck lays out the deck in the order the engine deals it, the stacked hole cards from the
+// left of the button round to the button, then the board, then everything else, stud deals its
+// upcards in between so only flop and draw games can be stacked
+func (sc *scenario) stackDeck() {
+	sc.t.Helper()
+	s := sc.e.state
+	rest := s.variant.newDeck()
+	used := append([]poker.Card{}, sc.boardCards...)
+	for _, cards := range sc.holeCards {
+		used = append(used, cards...)
+	}
+	remaining := make([]poker.Card, 0, rest.remaining())
+	for _, card := range rest.draw(rest.remaining()) {
+		if !containsCard(used, card) {
+			remaining = append(remaining, card)
+		}
+	}
+
+	cards := make([]poker.Card, 0, len(remaining)+len(used))
+	pointer := s.dealer.nextInHand
+	for {
+		dealt, ok := sc.holeCards[pointer.user]
+		if !ok {
+			dealt, remaining = remaining[:s.variant.holeCards], remaining[s.variant.holeCards:]
+		} else if len(dealt) != s.variant.holeCards {
+			sc.t.Fatalf("Expected %d hole cards for %v, got %v", s.variant.holeCards, pointer.user, dealt)
+		}
+		cards = append(cards, dealt...)
+		if pointer == s.dealer {
+			break
+		}
+		pointer = pointer.nextInHand
+	}
+	cards = append(cards, sc.boardCards...)
+	s.deck = &deck{cards: append(cards, remaining...)}
+}
+
+func (sc *scenario) isLegal(p *player, step step) bool {
+	legal := sc.e.state.legalActions(p, sc.e.engineState)
+	switch step.EngineCommand {
+	case "fold":
+		return legal.Fold
+	case "check":
+		return legal.Check
+	case "call":
+		return legal.Call
+	case "bet":
+		return legal.Bet && step.Chips >= legal.MinBet && step.Chips <= legal.MaxBet
+	case "runIt":
+		return legal.RunIt
+	case "discard":
+		return legal.Discard
+	case "show":
+		return legal.Show
+	case "addChips":
+		return true
+	case "preAction":
+		return p.nextInHand != nil && !p.isAllIn()
+	}
+	return false
+}
+
+func (sc *scenario) expectStacks(stacks map[string]float64) *scenario {
+	sc.t.Helper()
+	for user, chips := range stacks {
+		if got := sc.e.state.players[user].chips; math.Abs(got-chips) > 0.001 {
+			sc.t.Errorf("Expected %v to have %v, got %v", user, chips, got)
+		}
+	}
+	return sc
+}
+
+// expectPot checks the most that was in the pot when it was paid out
+func (sc *scenario) expectPot(pot float64) *scenario {
+	sc.t.Helper()
+	if math.Abs(sc.pot-pot) > 0.001 {
+		sc.t.Errorf("Expected a pot of %v, got %v", pot, sc.pot)
+	}
+	return sc
+}
+
+// expectAction checks the hand history has the action in it
+func (sc *scenario) expectAction(user string, action string, amount float64) *scenario {
+	sc.t.Helper()
+	for _, a := range sc.e.state.history {
+		if a.User == user && a.Action == action && math.Abs(a.Amount-amount) < 0.001 {
+			return sc
+		}
+	}
+	sc.t.Errorf("Expected %v to %v %v, got %+v", user, action, amount, sc.e.state.history)
+	return sc
+}
+
+// expectShown checks the player showed exactly these cards, nobody else's cards are in the history
+func (sc *scenario) expectShown(user string, cards string) *scenario {
+	sc.t.Helper()
+	shown := make([]poker.Card, 0)
+	for _, a := range sc.e.state.history {
+		if a.User == user && a.Action == "show" {
+			shown = append(shown, a.Cards...)
+		}
+	}
+	if want := sc.parse(cards); len(shown) != len(want) || !CompareCardSlices(shown, want) {
+		sc.t.Errorf("Expected %v to show %v, got %v", user, want, shown)
+	}
+	return sc
+}
+
+// expectShowdownOrder checks who showed or mucked at the showdown and in what order
+func (sc *scenario) expectShowdownOrder(users ...string) *scenario {
+	sc.t.Helper()
+	order := make([]string, 0)
+	for _, a := range sc.e.state.history {
+		if a.Action == "show" || a.Action == "muck" {
+			order = append(order, a.User)
+		}
+	}
+	if strings.Join(order, " ") != strings.Join(users, " ") {
+		sc.t.Errorf("Expected the showdown to go %v, got %v", users, order)
+	}
+	return sc
+}
+
+// expectWinners checks who finished the hand with more than they started it with
+func (sc *scenario) expectWinners(users ...string) *scenario {
+	sc.t.Helper()
+	winners := make([]string, 0)
+	for user, chips := range sc.stacks {
+		if sc.e.state.players[user].chips > chips+0.001 {
+			winners = append(winners, user)
+		}
+	}
+	if len(winners) != len(users) {
+		sc.t.Errorf("Expected %v to win, got %v", users, winners)
+		return sc
+	}
+	for _, user := range users {
+		if sc.e.state.players[user].chips <= sc.stacks[user]+0.001 {
+			sc.t.Errorf("Expected %v to win, got %v", users, winners)
+		}
+	}
+	return sc
+}
+
+func TestScenarioShowdown(t *testing.T) {
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		seat("carol", 7, 100).
+		button("alice").
+		deal("alice", "As Ad").
+		deal("bob", "Ks Kd").
+		deal("carol", "7c 2d").
+		board("2c 3c 7h 8h 9d").
+		act("alice", "call").
+		bet("bob", 10).
+		act("carol", "fold").
+		act("alice", "call").
+		act("bob", "check").
+		act("alice", "check").
+		bet("bob", 20).
+		act("alice", "call").
+		act("bob", "check").
+		act("alice", "check").
+		run().
+		expectPot(62).
+		expectWinners("alice").
+		expectStacks(map[string]float64{"alice": 132, "bob": 70, "carol": 98})
+}
+
+func TestScenarioSplitPot(t *testing.T) {
+	// the board is a straight nobody can beat
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		deal("alice", "2s 3d").
+		deal("bob", "4s 5d").
+		board("Tc Jc Qh Kh Ad").
+		bet("alice", 100).
+		act("bob", "call").
+		run().
+		expectPot(200).
+		expectWinners().
+		expectStacks(map[string]float64{"alice": 100, "bob": 100})
+}
+
+func TestScenarioRunItTwice(t *testing.T) {
+	// each street is dealt to both boards in turn, kings fill up on the first and aces hold on the second
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2, RunItTwice: true}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		deal("alice", "As Ad").
+		deal("bob", "Ks Kd").
+		board("2c 3c 7h 8h 9d 2h 3h 7s Kh 9c").
+		bet("alice", 100).
+		act("bob", "call").
+		runIt("alice", 2).
+		runIt("bob", 3).
+		run().
+		expectPot(200).
+		expectStacks(map[string]float64{"alice": 100, "bob": 100})
+}
+
+func TestScenarioShortAllInDoesNotReopenBetting(t *testing.T) {
+	// bob's all in is 3 more than dave's raise to 10, dave and alice already acted on that raise and
+	// can only call, carol hasn't acted yet and can still raise
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 2, 13).
+		seat("carol", 3, 100).
+		seat("dave", 4, 100).
+		button("alice").
+		bet("dave", 10).
+		act("alice", "call").
+		bet("bob", 13).
+		act("carol", "call").
+		cantRaise("dave").
+		act("dave", "call").
+		cantRaise("alice").
+		act("alice", "call").
+		act("carol", "check").
+		act("dave", "check").
+		act("alice", "check").
+		act("carol", "check").
+		act("dave", "check").
+		act("alice", "check").
+		act("carol", "check").
+		act("dave", "check").
+		act("alice", "check").
+		run().
+		expectPot(52)
+}
+
+func TestScenarioFullRaiseReopensBetting(t *testing.T) {
+	// carol raises behind bob's short all in, dave faces a full raise again and can raise
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 2, 13).
+		seat("carol", 3, 100).
+		seat("dave", 4, 100).
+		button("alice").
+		bet("dave", 10).
+		act("alice", "call").
+		bet("bob", 13).
+		bet("carol", 30).
+		bet("dave", 100).
+		act("alice", "fold").
+		act("carol", "fold").
+		run().
+		expectPot(83).
+		expectAction("dave", "uncalledBet", 70)
+}
+
+func TestScenarioShortAllInsAddUpToAFullRaise(t *testing.T) {
+	// on the flop bob's all in alone is short of a full raise over dave's bet but carol's puts dave
+	// and alice 11 behind, more than the 10 they'd need to face to raise again
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 2, 16).
+		seat("carol", 3, 23).
+		seat("dave", 4, 100).
+		button("alice").
+		act("dave", "call").
+		act("alice", "call").
+		act("bob", "call").
+		act("carol", "check").
+		act("bob", "check").
+		act("carol", "check").
+		bet("dave", 10).
+		act("alice", "call").
+		bet("bob", 14).
+		bet("carol", 21).
+		bet("dave", 40).
+		act("alice", "fold").
+		run().
+		expectPot(8 + 10 + 14 + 21 + 21)
+}
+
+func TestScenarioUncalledBet(t *testing.T) {
+	// bob can only call 40 of alice's shove, the other 60 goes back to her before the pot is played
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 40).
+		button("alice").
+		deal("alice", "As Ad").
+		deal("bob", "Ks Kd").
+		board("2c 3c 7h 8h 9d").
+		bet("alice", 100).
+		act("bob", "call").
+		run().
+		expectPot(80).
+		expectAction("alice", "uncalledBet", 60).
+		expectAction("alice", "win", 80).
+		expectStacks(map[string]float64{"alice": 140, "bob": 0})
+}
+
+func TestScenarioShowdownOrder(t *testing.T) {
+	// carol bet the river so she shows first, alice can't beat her kings and mucks, bob's trips
+	// beat them and he has to show
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		seat("carol", 7, 100).
+		button("alice").
+		deal("alice", "Qd Qh").
+		deal("bob", "7c 7d").
+		deal("carol", "Ks Qs").
+		board("2c 3c 7h 8h Kd").
+		act("alice", "call").
+		act("bob", "call").
+		act("carol", "check").
+		act("bob", "check").
+		act("carol", "check").
+		act("alice", "check").
+		act("bob", "check").
+		act("carol", "check").
+		act("alice", "check").
+		act("bob", "check").
+		bet("carol", 10).
+		act("alice", "call").
+		act("bob", "call").
+		run().
+		expectShowdownOrder("carol", "alice", "bob").
+		expectShown("carol", "Ks Qs").
+		expectShown("alice", "").
+		expectShown("bob", "7c 7d").
+		expectAction("alice", "muck", 0).
+		expectWinners("bob")
+}
+
+func TestScenarioCheckedDownShowsFromLeftOfButton(t *testing.T) {
+	// nobody bet the river so bob shows first, alice's ace high can't beat his pair
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		deal("alice", "As 4d").
+		deal("bob", "9s 9c").
+		board("2c 3c 7h 8h Kd").
+		act("alice", "call").
+		act("bob", "check").
+		act("bob", "check").
+		act("alice", "check").
+		act("bob", "check").
+		act("alice", "check").
+		act("bob", "check").
+		act("alice", "check").
+		run().
+		expectShowdownOrder("bob", "alice").
+		expectShown("bob", "9s 9c").
+		expectShown("alice", "").
+		expectWinners("bob")
+}
+
+func TestScenarioAllInHandsAreTabled(t *testing.T) {
+	// with a player all in every hand is turned over, even the one that loses
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		deal("alice", "As Ad").
+		deal("bob", "Ks Kd").
+		board("2c 3c 7h 8h 9d").
+		bet("alice", 100).
+		act("bob", "call").
+		run().
+		expectShown("alice", "As Ad").
+		expectShown("bob", "Ks Kd").
+		expectWinners("alice")
+}
+
+func TestScenarioShowAfterEveryoneFolds(t *testing.T) {
+	// alice doesn't have to show after bob folds but can show one of her cards
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		deal("alice", "As 2d").
+		deal("bob", "Ks Kd").
+		bet("alice", 6).
+		act("bob", "fold").
+		show("alice", "As").
+		run().
+		expectShown("alice", "As").
+		expectShown("bob", "").
+		expectShowdownOrder("alice").
+		expectWinners("alice")
+}
+
+func TestScenarioPreActions(t *testing.T) {
+	// bob's call any and carol's check go as soon as alice limps, on the flop carol's check/fold
+	// folds to bob's bet and alice's check is taken back so she has to decide
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		seat("carol", 7, 100).
+		button("alice").
+		preAct("bob", "callAny").
+		preAct("carol", "check").
+		act("alice", "call").
+		preAct("carol", "checkFold").
+		preAct("alice", "check").
+		bet("bob", 10).
+		act("alice", "fold").
+		run().
+		expectAction("bob", "call", 1).
+		expectAction("carol", "check", 0).
+		expectAction("carol", "fold", 0).
+		expectWinners("bob").
+		expectStacks(map[string]float64{"alice": 98, "bob": 104, "carol": 98})
+}
+
+func TestScenarioTopUpDuringHand(t *testing.T) {
+	// alice's top-up waits for the hand to end, she loses her small blind from the stack she had
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		topUp("alice", 40).
+		act("alice", "fold").
+		run().
+		expectStacks(map[string]float64{"alice": 139, "bob": 101})
+}
+
+func TestScenarioCheckFacingBet(t *testing.T) {
+	// the small blind owes the rest of the big blind and can't check it, they're still to act
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		refused("alice", "check", Event{}).
+		act("alice", "fold").
+		run().
+		expectWinners("bob")
+}
+
+func TestScenarioShowOnlyOnceBettingIsOver(t *testing.T) {
+	// nobody shows mid hand, bob can't show once he's folded and alice can only show her own cards
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 4, 100).
+		button("alice").
+		deal("alice", "As 2d").
+		deal("bob", "Ks Kd").
+		refused("alice", "show", Event{}).
+		bet("alice", 6).
+		act("bob", "fold").
+		refused("bob", "show", Event{}).
+		refused("alice", "show", Event{Cards: []string{"Ks"}}).
+		show("alice", "").
+		run().
+		expectShown("alice", "As 2d").
+		expectShown("bob", "").
+		expectWinners("alice")
+}
+
+func TestScenarioPreActionInvalidatedByRaise(t *testing.T) {
+	// bob can't pre check the big blind, dave's raise takes back his call straight away while alice
+	// still has to act, carol's call any still stands
+	newScenario(t, StartGameRequest{SmallBlind: 1, BigBlind: 2}).
+		seat("alice", 1, 100).
+		seat("bob", 3, 100).
+		seat("carol", 5, 100).
+		seat("dave", 7, 100).
+		button("alice").
+		refused("bob", "preAction", Event{PreAction: "check"}).
+		preAct("bob", "call").
+		preAct("carol", "callAny").
+		expectPreAction("bob", "call").
+		bet("dave", 6).
+		expectPreAction("bob", "").
+		expectPreAction("carol", "callAny").
+		act("alice", "fold").
+		act("bob", "call").
+		bet("bob", 10).
+		act("carol", "fold").
+		act("dave", "fold").
+		run().
+		expectAction("carol", "call", 4).
+		expectWinners("bob")
+}
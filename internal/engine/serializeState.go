@@ -1,6 +1,10 @@
 package engine
 
-import "github.com/chehsunliu/poker"
+import (
+	"sort"
+
+	"github.com/chehsunliu/poker"
+)
 
 type SerializePlayer struct {
 	User string `json:"user"`
@@ -9,8 +13,11 @@ type SerializePlayer struct {
 	ChipsInPot float64  `json:"chipsInPot"`
 	TimeBank float64 `json:"timeBank"`
 	HoleCards []poker.Card `json:"holeCards"`
+	UpCards []poker.Card `json:"upCards"`
     Spotlight bool `json:"spotlight"`
     Dealer bool `json:"dealer"`
+    RunItVote int `json:"runItVote"`
+    Bot bool `json:"bot"`
 }
 
 func createSerializePlayer(p *player, s *state) SerializePlayer {
@@ -20,9 +27,12 @@ func createSerializePlayer(p *player, s *state) SerializePlayer {
         Chips: p.chips,
         ChipsInPot: p.chipsInPot,
         TimeBank: p.timeBank,
-        HoleCards: p.holeCards,
+        HoleCards: p.shownCards,
+        UpCards: p.upCards,
         Spotlight: p == s.spotlight,
         Dealer: p == s.dealer,
+        RunItVote: p.runItVote,
+        Bot: p.bot != nil,
     }
 }
 
@@ -35,15 +45,48 @@ type SerializeState struct {
     CurrentBet float64 `json:"currentBet"`
     MinRaise float64 `json:"minRaise"`
     CommunityCards []poker.Card `json:"communityCards"`
+    Boards [][]poker.Card `json:"boards"`
+    Runs int `json:"runs"`
+    RunItVote bool `json:"runItVote"`
+    Drawing bool `json:"drawing"`
+    Discarding bool `json:"discarding"`
+    BombPot bool `json:"bombPot"`
+    BombPotVotes int `json:"bombPotVotes"`
+    Game string `json:"game"`
+    Ante float64 `json:"ante"`
+    Structure string `json:"structure"`
+    NextGame RotationGame `json:"nextGame"`
+    HandsUntilNextGame int `json:"handsUntilNextGame"`
+    DealersChoice []RotationGame `json:"dealersChoice"`
+    Chooser string `json:"chooser"`
+    ChoosingGame bool `json:"choosingGame"`
 	Players map[int]SerializePlayer `json:"players"`
+    Pots []SerializePot `json:"pots"`
+    History []HandAction `json:"history"`
     GameStopped bool `json:"gameStopped"`
+    Paused bool `json:"paused"`
+    Owner string `json:"owner"`
+    PendingJoins []string `json:"pendingJoins"`
+    MinBuyIn float64 `json:"minBuyIn"`
+    MaxBuyIn float64 `json:"maxBuyIn"`
 }
 
-func createSerializeState(s *state, gameStopped bool) SerializeState {
+func createSerializeState(s *state, gameStopped bool, engineState engineState) SerializeState {
     serializePlayers := make(map[int]SerializePlayer)
     for _, player := range s.players {
         serializePlayers[player.seatId] = createSerializePlayer(player, s)
     }
+    pendingJoins := make([]string, 0, len(s.pendingJoins))
+    for user := range s.pendingJoins {
+        pendingJoins = append(pendingJoins, user)
+    }
+    sort.Strings(pendingJoins)
+    nextGame, handsUntilNextGame := s.nextGame()
+    // communityCards is the first board, clients that understand multiple boards use boards
+    var communityCards []poker.Card
+    if len(s.boards) > 0 {
+        communityCards = s.boards[0]
+    }
 
     return SerializeState{
         ChannelCommand: "sendState",
@@ -53,8 +96,59 @@ func createSerializeState(s *state, gameStopped bool) SerializeState {
         CollectedPot: s.collectedPot,
         CurrentBet: s.currentBet,
         MinRaise: s.minRaise,
-        CommunityCards: s.communityCards,
+        CommunityCards: communityCards,
+        Boards: s.boards,
+        Runs: s.runs,
+        RunItVote: engineState == StateRunItVote,
+        Drawing: engineState == StateDraw,
+        Discarding: engineState == StateDiscard,
+        BombPot: s.bombPot,
+        BombPotVotes: len(s.bombPotVotes),
+        Game: s.variant.name,
+        Ante: s.ante,
+        Structure: s.structure,
+        NextGame: nextGame,
+        HandsUntilNextGame: handsUntilNextGame,
+        DealersChoice: s.dealersChoice,
+        Chooser: s.chooser,
+        ChoosingGame: engineState == StateChooseGame,
         Players: serializePlayers,
+        Pots: createSerializePots(s.pots),
+        History: s.history,
         GameStopped: gameStopped,
+        Paused: s.paused,
+        Owner: s.owner,
+        PendingJoins: pendingJoins,
+        MinBuyIn: s.minBuyIn,
+        MaxBuyIn: s.maxBuyIn,
+    }
+}
+
+// SerializePlayerView is only for its player, it's the one place their hole cards are sent
+type SerializePlayerView struct {
+    ChannelCommand string `json:"channelCommand"`
+    User string `json:"user"`
+    View PlayerView `json:"view"`
+}
+
+func createSerializePlayerView(s *state, p *player) SerializePlayerView {
+    return SerializePlayerView{
+        ChannelCommand: "playerView",
+        User: p.user,
+        View: createPlayerView(s, p),
+    }
+}
+
+type SerializeError struct {
+    ChannelCommand string `json:"channelCommand"`
+    User string `json:"user"`
+    Message string `json:"message"`
+}
+
+func createSerializeError(user string, err error) SerializeError {
+    return SerializeError{
+        ChannelCommand: "error",
+        User: user,
+        Message: err.Error(),
     }
 }
\ No newline at end of file
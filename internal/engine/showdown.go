@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"log"
+
+	"github.com/chehsunliu/poker"
+)
+
+// showHands turns the hands over in showdown order. Once anyone is all in every hand is tabled,
+// otherwise each player shows only if they win or tie a share of a pot they're in against the
+// hands already shown and mucks if they can't, so every winner shows and nobody else has to.
+func (s *state) showHands() {
+	allIn := false
+	for _, p := range s.playersInHand() {
+		allIn = allIn || p.isAllIn()
+	}
+	shown := make([]*player, 0)
+	for _, p := range s.showdownOrder() {
+		if allIn || s.mustShow(p, shown) {
+			s.showCards(p, p.holeCards)
+			shown = append(shown, p)
+		} else {
+			log.Println(p.user, "mucks")
+			s.record(p.user, "muck", 0)
+		}
+	}
+}
+
+// showdownOrder starts with whoever bet or raised last on the final street, if nobody did it
+// starts with the first player left of the button, and goes round the table from there
+func (s *state) showdownOrder() []*player {
+	first := s.psuedoDealer.nextInHand
+	if s.aggressor != nil && s.aggressor.nextInHand != nil && s.aggressorStreet == s.street {
+		first = s.aggressor
+	}
+	order := make([]*player, 0)
+	pointer := first
+	for {
+		order = append(order, pointer)
+		pointer = pointer.nextInHand
+		if pointer == first {
+			return order
+		}
+	}
+}
+
+// mustShow is true if the player's hand beats or ties the best shown hand for any share of a pot
+// they're in, or nobody they're up against in it has shown yet
+func (s *state) mustShow(p *player, shown []*player) bool {
+	for _, payout := range s.payouts {
+		board := s.boards[payout.board]
+		for _, pot := range s.pots {
+			if !containsPlayer(pot.eligible, p) {
+				continue
+			}
+			contenders := []*player{p}
+			for _, other := range shown {
+				if containsPlayer(pot.eligible, other) {
+					contenders = append(contenders, other)
+				}
+			}
+			if containsPlayer(findBestHand(contenders, board, s.payoutEvaluator(payout)), p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// showCards turns cards face up for everyone, the ones already showing are left alone
+func (s *state) showCards(p *player, cards []poker.Card) {
+	turned := make([]poker.Card, 0, len(cards))
+	for _, card := range cards {
+		if !containsCard(p.shownCards, card) && !containsCard(turned, card) {
+			turned = append(turned, card)
+		}
+	}
+	if len(turned) == 0 {
+		return
+	}
+	log.Println(p.user, "shows", turned)
+	p.shownCards = append(p.shownCards, turned...)
+	s.recordCards(p.user, "show", turned)
+}
+
+func containsPlayer(players []*player, p *player) bool {
+	for _, other := range players {
+		if other == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/wegman7/game-engine/config"
+)
+
+// Simulation plays a table of bots against each other as fast as the engine can run, with
+// no connection and on a fake clock, checking the invariants after every tick
+type Simulation struct {
+	Table StartGameRequest
+	// Bots has one bot name per seat
+	Bots  []string
+	Chips float64
+	Hands int
+}
+
+// SimulationReport is how the simulation went, Net is what each seat won or lost counting
+// the rebuys it took after busting
+type SimulationReport struct {
+	Hands          int
+	Crashes        []error
+	Elapsed        time.Duration
+	HandsPerSecond float64
+	Net            map[string]float64
+}
+
+// a hand that takes this many ticks isn't going to finish
+const maxTicksPerHand = 10000
+
+// the simulation has nobody watching, a second passes every tick so pauses are over almost
+// right away and anything waiting on a player who never answers times out in a few ticks
+const simulatedTick = time.Second
+
+// Simulate plays the hands, a crash is recorded and the table starts over with fresh stacks so
+// one bug doesn't end the run
+func Simulate(sim Simulation) (SimulationReport, error) {
+	if err := sim.Table.validate(); err != nil {
+		return SimulationReport{}, err
+	}
+	if len(sim.Bots) < 2 || len(sim.Bots) > config.AppConfig.MAX_PLAYERS {
+		return SimulationReport{}, fmt.Errorf("need between 2 and %d bots", config.AppConfig.MAX_PLAYERS)
+	}
+	for _, name := range sim.Bots {
+		if _, ok := bots[name]; !ok {
+			return SimulationReport{}, fmt.Errorf("unknown bot: %s", name)
+		}
+	}
+	if sim.Chips <= 0 {
+		sim.Chips = 100 * sim.Table.BigBlind
+	}
+
+	report := SimulationReport{Net: make(map[string]float64)}
+	started := time.Now()
+	for report.Hands < sim.Hands {
+		hands, net, err := sim.playTable(sim.Hands - report.Hands)
+		report.Hands += hands
+		for user, chips := range net {
+			report.Net[user] += chips
+		}
+		if err != nil {
+			err = fmt.Errorf("hand %d: %w", report.Hands+1, err)
+			log.Println("Simulation crashed: ", err)
+			report.Crashes = append(report.Crashes, err)
+			// a crash is the hand that didn't finish, count it so a table that always crashes still ends
+			report.Hands++
+		}
+	}
+	report.Elapsed = time.Since(started)
+	if seconds := report.Elapsed.Seconds(); seconds > 0 {
+		report.HandsPerSecond = float64(report.Hands) / seconds
+	}
+	return report, nil
+}
+
+// playTable seats the bots at a new table and plays until the hands are done or something
+// breaks, a panic anywhere in the engine is returned as an error
+func (sim Simulation) playTable(hands int) (played int, net map[string]float64, err error) {
+	e := createEngine(nil, sim.Table)
+	clock := newFakeClock()
+	e.state.clock = clock
+	buyIns := make(map[string]float64)
+	net = make(map[string]float64)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in %v: %v", e.engineState, r)
+		}
+		for user, p := range e.state.players {
+			net[user] = p.chips + p.chipsInHand - buyIns[user]
+		}
+	}()
+
+	for seat, name := range sim.Bots {
+		user := fmt.Sprintf("%s-%d", name, seat)
+		join := Event{EngineCommand: "join", SeatId: seat, User: user, Chips: sim.Chips, Bot: name}
+		if err := e.join(join); err != nil {
+			return 0, net, err
+		}
+		buyIns[user] = sim.Chips
+	}
+	e.startNextHand()
+
+	ticks := 0
+	for played < hands {
+		e.tick()
+		if err := e.state.checkInvariants(e.engineState); err != nil {
+			return played, net, fmt.Errorf("invariant in %v: %w", e.engineState, err)
+		}
+		e.runBots()
+		clock.advance(simulatedTick)
+
+		ticks++
+		switch e.engineState {
+		case StatePauseAfterEndHand:
+			played++
+			ticks = 0
+			// the invariants check the chips during a hand, between hands they should all be back in stacks
+			if leaked := sumChips(buyIns) - e.state.totalChips(); math.Abs(leaked) > 0.001 {
+				return played, net, fmt.Errorf("%.4f chips went missing", leaked)
+			}
+			// busted bots buy back in so the table never breaks up
+			for user, p := range e.state.players {
+				if p.chips == 0 {
+					p.chips = sim.Chips
+					p.sittingOut = false
+					buyIns[user] += sim.Chips
+				}
+			}
+		case StateProcessSitCommands:
+			return played, net, errors.New("table stopped between hands")
+		}
+		if ticks > maxTicksPerHand {
+			return played, net, fmt.Errorf("stuck in %v", e.engineState)
+		}
+	}
+	return played, net, nil
+}
+
+func sumChips(chips map[string]float64) float64 {
+	total := 0.0
+	for _, amount := range chips {
+		total += amount
+	}
+	return total
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wegman7/game-engine/config"
+)
+
+func TestSimulate(t *testing.T) {
+	config.AppConfig.MAX_PLAYERS = 9
+	config.AppConfig.MAX_RUNS = 3
+	for game := range variants {
+		report, err := Simulate(Simulation{
+			Table: StartGameRequest{SmallBlind: 1, BigBlind: 2, Game: game, RunItTwice: true},
+			Bots:  []string{"tag", "callingStation", "random", "random", "callingStation", "tag"},
+			Hands: 200,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Hands != 200 || len(report.Crashes) > 0 {
+			t.Errorf("Expected 200 %v hands without a crash, got %v hands and %v", game, report.Hands, report.Crashes)
+		}
+		net := 0.0
+		for _, chips := range report.Net {
+			net += chips
+		}
+		if math.Abs(net) > 0.001 {
+			t.Errorf("Expected the %v winnings to add up to nothing, got %v", game, net)
+		}
+	}
+
+	if _, err := Simulate(Simulation{Table: StartGameRequest{SmallBlind: 1, BigBlind: 2}, Bots: []string{"tag", "nobody"}}); err == nil {
+		t.Errorf("Expected an unknown bot to be rejected")
+	}
+}
@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TableSnapshot is everything needed to put players back in their seats after a restart
+type TableSnapshot struct {
+	RoomName     string         `json:"roomName"`
+	SmallBlind   float64        `json:"smallBlind"`
+	BigBlind     float64        `json:"bigBlind"`
+	DealerSeat   int            `json:"dealerSeat"`
+	Paused       bool           `json:"paused"`
+	Owner        string         `json:"owner"`
+	Private      bool           `json:"private"`
+	MinBuyIn     float64        `json:"minBuyIn"`
+	MaxBuyIn     float64        `json:"maxBuyIn"`
+	RunItTwice   bool           `json:"runItTwice"`
+	BombPotEvery int            `json:"bombPotEvery"`
+	BombPotAnte  float64        `json:"bombPotAnte"`
+	Game         string         `json:"game"`
+	Ante         float64        `json:"ante"`
+	Structure    string         `json:"structure"`
+	Rotation     []RotationGame `json:"rotation"`
+	RotateEvery  int            `json:"rotateEvery"`
+	RotateOrbits int            `json:"rotateOrbits"`
+	// where the table is in its rotation, Game and Structure are the game being played
+	RotationIndex int `json:"rotationIndex"`
+	GameHands     int `json:"gameHands"`
+	// dealer's choice tables come back with a new choice, DefaultGame is the fallback
+	DealersChoice []RotationGame   `json:"dealersChoice"`
+	DefaultGame   RotationGame     `json:"defaultGame"`
+	Players       []PlayerSnapshot `json:"players"`
+}
+
+type PlayerSnapshot struct {
+	SeatId     int     `json:"seatId"`
+	User       string  `json:"user"`
+	Chips      float64 `json:"chips"`
+	SittingOut bool    `json:"sittingOut"`
+	// Bot names the bot playing the seat, empty for a person
+	Bot string `json:"bot"`
+}
+
+type SnapshotStore interface {
+	Save(snapshot TableSnapshot) error
+	LoadAll() ([]TableSnapshot, error)
+	Delete(roomName string) error
+}
+
+// snapshots is nil until UseSnapshotStore is called, in which case nothing is persisted
+var snapshots SnapshotStore
+
+func UseSnapshotStore(store SnapshotStore) {
+	snapshots = store
+}
+
+// RestoreEngines starts an engine for every table found in the snapshot store
+func RestoreEngines() error {
+	if snapshots == nil {
+		return nil
+	}
+
+	tables, err := snapshots.LoadAll()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if !reserveEngine(table.RoomName) {
+			continue
+		}
+		log.Println("Restoring engine for room", table.RoomName, "with", len(table.Players), "players")
+		go RestoreEngineConn(table)
+	}
+	return nil
+}
+
+func createSnapshot(roomName string, s *state) TableSnapshot {
+	snapshot := TableSnapshot{
+		RoomName:      roomName,
+		SmallBlind:    s.smallBlind,
+		BigBlind:      s.bigBlind,
+		DealerSeat:    -1,
+		Paused:        s.paused,
+		Owner:         s.owner,
+		Private:       s.private,
+		MinBuyIn:      s.minBuyIn,
+		MaxBuyIn:      s.maxBuyIn,
+		RunItTwice:    s.runItTwice,
+		BombPotEvery:  s.bombPotEvery,
+		BombPotAnte:   s.bombPotAnte,
+		Game:          s.variant.name,
+		Ante:          s.ante,
+		Structure:     s.structure,
+		Rotation:      s.rotation,
+		RotateEvery:   s.rotateEvery,
+		RotateOrbits:  s.rotateOrbits,
+		RotationIndex: s.rotationIndex,
+		GameHands:     s.gameHands,
+		DealersChoice: s.dealersChoice,
+		DefaultGame:   s.defaultGame,
+		Players:       make([]PlayerSnapshot, 0, len(s.players)),
+	}
+	// blinds waiting for the next hand are the level the table comes back at
+	if s.nextBigBlind != 0 {
+		snapshot.SmallBlind = s.nextSmallBlind
+		snapshot.BigBlind = s.nextBigBlind
+	}
+	if s.dealer == nil {
+		return snapshot
+	}
+
+	snapshot.DealerSeat = s.dealer.seatId
+	pointer := s.dealer
+	for {
+		snapshot.Players = append(snapshot.Players, PlayerSnapshot{
+			SeatId:     pointer.seatId,
+			User:       pointer.user,
+			Chips:      pointer.chips,
+			SittingOut: pointer.sittingOut,
+			Bot:        pointer.botName,
+		})
+		pointer = pointer.next
+		if pointer == s.dealer {
+			return snapshot
+		}
+	}
+}
+
+// restoreState seats every player from the snapshot and puts the dealer chip back where it was
+func restoreState(snapshot TableSnapshot) (*state, error) {
+	s := createTableState(snapshot.startGameRequest())
+	s.paused = snapshot.Paused
+	// a rotating table comes back on the game it was playing, not the start of the rotation
+	if len(s.rotation) > 0 {
+		s.rotationIndex = snapshot.RotationIndex % len(s.rotation)
+		s.gameHands = snapshot.GameHands
+		s.setGame(snapshot.Game, snapshot.Structure)
+	}
+	if len(s.dealersChoice) > 0 {
+		s.defaultGame = snapshot.DefaultGame
+	}
+	for _, ps := range snapshot.Players {
+		p := createPlayer(Event{SeatId: ps.SeatId, User: ps.User, Chips: ps.Chips})
+		p.sittingOut = ps.SittingOut
+		if ps.Bot != "" {
+			bot, err := newBot(ps.Bot)
+			if err != nil {
+				return nil, err
+			}
+			p.bot, p.botName = bot, ps.Bot
+		}
+		if err := s.addPlayer(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.dealer == nil {
+		return s, nil
+	}
+	pointer := s.dealer
+	for pointer.seatId != snapshot.DealerSeat {
+		pointer = pointer.next
+		if pointer == s.dealer {
+			return nil, fmt.Errorf("dealer seat %d is empty", snapshot.DealerSeat)
+		}
+	}
+	s.dealer = pointer
+	return s, nil
+}
+
+func (snapshot TableSnapshot) startGameRequest() StartGameRequest {
+	return StartGameRequest{
+		RoomName:      snapshot.RoomName,
+		SmallBlind:    snapshot.SmallBlind,
+		BigBlind:      snapshot.BigBlind,
+		Owner:         snapshot.Owner,
+		Private:       snapshot.Private,
+		MinBuyIn:      snapshot.MinBuyIn,
+		MaxBuyIn:      snapshot.MaxBuyIn,
+		RunItTwice:    snapshot.RunItTwice,
+		BombPotEvery:  snapshot.BombPotEvery,
+		BombPotAnte:   snapshot.BombPotAnte,
+		Game:          snapshot.Game,
+		Ante:          snapshot.Ante,
+		Structure:     snapshot.Structure,
+		Rotation:      snapshot.Rotation,
+		RotateEvery:   snapshot.RotateEvery,
+		RotateOrbits:  snapshot.RotateOrbits,
+		DealersChoice: snapshot.DealersChoice,
+	}
+}
+
+func (e *engine) saveSnapshot() {
+	if snapshots == nil {
+		return
+	}
+	if err := snapshots.Save(createSnapshot(e.roomName, e.state)); err != nil {
+		log.Println("Error saving snapshot: ", err)
+	}
+}
+
+func deleteSnapshot(roomName string) {
+	if snapshots == nil {
+		return
+	}
+	if err := snapshots.Delete(roomName); err != nil {
+		log.Println("Error deleting snapshot: ", err)
+	}
+}
+
+// fileSnapshotStore keeps one json file per room in dir
+type fileSnapshotStore struct {
+	dir string
+}
+
+func NewFileSnapshotStore(dir string) (SnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	return &fileSnapshotStore{dir: dir}, nil
+}
+
+func (f *fileSnapshotStore) path(roomName string) string {
+	return filepath.Join(f.dir, url.PathEscape(roomName)+".json")
+}
+
+func (f *fileSnapshotStore) Save(snapshot TableSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	// write to a temp file first so a crash mid-write never leaves a truncated snapshot
+	tmp := f.path(snapshot.RoomName) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return os.Rename(tmp, f.path(snapshot.RoomName))
+}
+
+func (f *fileSnapshotStore) LoadAll() ([]TableSnapshot, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot dir: %w", err)
+	}
+
+	tables := make([]TableSnapshot, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(f.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		snapshot := TableSnapshot{}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Println("Skipping corrupt snapshot", entry.Name(), ":", err)
+			continue
+		}
+		tables = append(tables, snapshot)
+	}
+	return tables, nil
+}
+
+func (f *fileSnapshotStore) Delete(roomName string) error {
+	err := os.Remove(f.path(roomName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
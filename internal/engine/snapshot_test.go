@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s := createState(1, 2, 30)
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 250})
+	p3 := createPlayer(Event{SeatId: 8, User: "user3", Chips: 0})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+	p3.sittingOut = true
+	p1.bot, p1.botName = callingStationBot{}, "callingStation"
+	s.dealer = p2
+
+	store, err := NewFileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Expected nil, got %s", err.Error())
+	}
+	if err := store.Save(createSnapshot("room1", s)); err != nil {
+		t.Fatalf("Expected nil, got %s", err.Error())
+	}
+
+	tables, err := store.LoadAll()
+	if err != nil {
+		t.Fatalf("Expected nil, got %s", err.Error())
+	}
+	if len(tables) != 1 || tables[0].RoomName != "room1" {
+		t.Fatalf("Expected one snapshot for room1, got %v", tables)
+	}
+
+	restored, err := restoreState(tables[0])
+	if err != nil {
+		t.Fatalf("Expected nil, got %s", err.Error())
+	}
+	if restored.dealer.user != "user2" {
+		t.Errorf("Expected dealer to be user2, got %v", restored.dealer.user)
+	}
+	if restored.printPlayers() != "5 -> 8 -> 1 -> 5" {
+		t.Errorf("Expected 5 -> 8 -> 1 -> 5, got %v", restored.printPlayers())
+	}
+	if restored.players["user2"].chips != 250 || restored.players["user1"].chips != 100 {
+		t.Errorf("Expected 250, 100, got %v, %v", restored.players["user2"].chips, restored.players["user1"].chips)
+	}
+	if _, ok := restored.players["user1"].bot.(callingStationBot); !ok || restored.players["user2"].bot != nil {
+		t.Errorf("Expected user1 back as a calling station and user2 as a person, got %v and %v", restored.players["user1"].bot, restored.players["user2"].bot)
+	}
+	if !restored.players["user3"].sittingOut {
+		t.Errorf("Expected user3 to be sitting out")
+	}
+	if restored.smallBlind != 1 || restored.bigBlind != 2 {
+		t.Errorf("Expected blinds 1/2, got %v/%v", restored.smallBlind, restored.bigBlind)
+	}
+
+	// snapshots are only taken between hands of a game that's going, a restored table always deals on
+	e, err := restoreEngine(nil, tables[0])
+	if err != nil {
+		t.Fatalf("Expected nil, got %s", err.Error())
+	}
+	if len(e.sitCommands) != 1 || e.sitCommands[0].EngineCommand != "startGame" {
+		t.Errorf("Expected the restored table to start its game, got %v", e.sitCommands)
+	}
+
+	if err := store.Delete("room1"); err != nil {
+		t.Fatalf("Expected nil, got %s", err.Error())
+	}
+	tables, _ = store.LoadAll()
+	if len(tables) != 0 {
+		t.Errorf("Expected no snapshots, got %v", tables)
+	}
+}
@@ -2,22 +2,97 @@ package engine
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 )
 
 type StartGameRequest struct {
 	RoomName  string `json:"roomName"`
 	SmallBlind  float64 `json:"smallBlind"`
 	BigBlind  float64 `json:"bigBlind"`
+	Owner  string `json:"owner"`
+	Private  bool `json:"private"`
+	MinBuyIn  float64 `json:"minBuyIn"`
+	MaxBuyIn  float64 `json:"maxBuyIn"`
+	RunItTwice  bool `json:"runItTwice"`
+	BombPotEvery  int `json:"bombPotEvery"`
+	BombPotAnte  float64 `json:"bombPotAnte"`
+	Game  string `json:"game"`
+	Ante  float64 `json:"ante"`
+	Structure  string `json:"structure"`
+	Rotation  []RotationGame `json:"rotation"`
+	RotateEvery  int `json:"rotateEvery"`
+	RotateOrbits  int `json:"rotateOrbits"`
+	DealersChoice  []RotationGame `json:"dealersChoice"`
+}
+
+func (req StartGameRequest) validate() error {
+	if _, err := lookupVariant(req.Game); err != nil {
+		return err
+	}
+	if err := verifyStructure(req.Structure); err != nil {
+		return err
+	}
+	if len(req.Rotation) > 0 && len(req.DealersChoice) > 0 {
+		return errors.New("a table can't rotate games and play dealer's choice")
+	}
+	if err := verifyRotation(req.DealersChoice); err != nil {
+		return err
+	}
+	return verifyRotation(req.Rotation)
 }
 
 type StartGameResponse struct {
 	Message string `json:"message"`
 }
 
-var runningEngines = make(map[string]struct{})
+// runningEngines maps a room to its engine, the engine is nil until the websocket connects
+var (
+	runningEngines   = make(map[string]*engine)
+	runningEnginesMu sync.Mutex
+)
+
+// reserveEngine claims the room, returns false if an engine is already running for it
+func reserveEngine(roomName string) bool {
+	runningEnginesMu.Lock()
+	defer runningEnginesMu.Unlock()
+	if _, ok := runningEngines[roomName]; ok {
+		return false
+	}
+	runningEngines[roomName] = nil
+	return true
+}
+
+func registerEngine(e *engine) {
+	runningEnginesMu.Lock()
+	defer runningEnginesMu.Unlock()
+	runningEngines[e.roomName] = e
+}
+
+func releaseEngine(roomName string) {
+	runningEnginesMu.Lock()
+	defer runningEnginesMu.Unlock()
+	delete(runningEngines, roomName)
+}
+
+func getEngine(roomName string) *engine {
+	runningEnginesMu.Lock()
+	defer runningEnginesMu.Unlock()
+	return runningEngines[roomName]
+}
+
+func listEngines() map[string]*engine {
+	runningEnginesMu.Lock()
+	defer runningEnginesMu.Unlock()
+	engines := make(map[string]*engine, len(runningEngines))
+	for roomName, e := range runningEngines {
+		engines[roomName] = e
+	}
+	return engines
+}
 
 func StartEngineHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("startEngineHandler")
@@ -27,12 +102,16 @@ func StartEngineHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, ok := runningEngines[req.RoomName]; ok {
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !reserveEngine(req.RoomName) {
 		http.Error(w, "Engine already running for room", http.StatusBadRequest)
 		return
 	}
-	runningEngines[req.RoomName] = struct{}{}
-	go CreateEngineConn(req.RoomName, req.SmallBlind, req.BigBlind)
+	go CreateEngineConn(req)
 	
 	responseData := StartGameResponse{
 		Message: fmt.Sprintf("Started engine for room %s", req.RoomName),
@@ -3,9 +3,8 @@ package engine
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math"
-	"sort"
+	"time"
 
 	"github.com/chehsunliu/poker"
 	"github.com/wegman7/game-engine/config"
@@ -19,6 +18,15 @@ const (
 	Flop
 	Turn
 	River
+	Predraw
+	FirstDraw
+	SecondDraw
+	ThirdDraw
+	ThirdStreet
+	FourthStreet
+	FifthStreet
+	SixthStreet
+	SeventhStreet
 )
 
 type state struct {
@@ -30,15 +38,67 @@ type state struct {
 	dealer           *player
 	psuedoDealer     *player
 	lastAggressor    *player
+	// aggressor is the last player to bet or raise, aggressorStreet is the street they did it on
+	aggressor        *player
+	aggressorStreet  street
+	// folded is everyone who folded this hand, in order
+	folded           []*player
 	street           street
 	pot              float64
 	collectedPot     float64
 	currentBet       float64
 	minRaise         float64
-	deck             *poker.Deck
-	communityCards   []poker.Card
+	deck             *deck
+	boards           [][]poker.Card
 	prevState        *state
 	chipsInHandTotal float64
+	nextSmallBlind   float64
+	nextBigBlind     float64
+	paused           bool
+	owner            string
+	private          bool
+	pendingJoins     map[string]Event
+	minBuyIn         float64
+	maxBuyIn         float64
+	departures       map[string]departure
+	runItTwice       bool
+	runs             int
+	runItDeadline    time.Time
+	pots             []pot
+	showdownPot      int
+	payouts          []payout
+	showdownPayout   int
+	handCount        int
+	bombPot          bool
+	bombPotEvery     int
+	bombPotAnte      float64
+	bombPotVotes     map[string]bool
+	variant          *variant
+	ante             float64
+	discards         []poker.Card
+	structure        string
+	bets             int
+	history          []HandAction
+	rotation         []RotationGame
+	rotationIndex    int
+	rotateEvery      int
+	rotateOrbits     int
+	gameHands        int
+	orbitSize        int
+	dealersChoice    []RotationGame
+	defaultGame      RotationGame
+	chooser          string
+	chooserSeat      int
+	gameChosen       bool
+	chooseDeadline   time.Time
+	discardDeadline  time.Time
+	clock            clock
+}
+
+// departure remembers the stack a player left with so they can't come straight back with less
+type departure struct {
+	chips float64
+	at    time.Time
 }
 
 func createState(smallBlind float64, bigBlind float64, timebankTotal float64) *state {
@@ -57,9 +117,15 @@ func createState(smallBlind float64, bigBlind float64, timebankTotal float64) *s
 		currentBet:       0.0,
 		minRaise:         0.0,
 		deck:             nil,
-		communityCards:   nil,
+		boards:           nil,
 		prevState:        nil,
 		chipsInHandTotal: 0.0,
+		pendingJoins:     make(map[string]Event),
+		departures:       make(map[string]departure),
+		bombPotVotes:     make(map[string]bool),
+		variant:          variants[defaultVariant],
+		structure:        noLimit,
+		clock:            realClock{},
 	}
 }
 
@@ -80,8 +146,9 @@ func (s *state) copy() *state {
         lastAggressor:    s.lastAggressor,
         street:           s.street,
         pot:              s.pot,
-        communityCards:   append([]poker.Card{}, s.communityCards...),
+        boards:           copyBoards(s.boards),
         chipsInHandTotal: s.chipsInHandTotal,
+        paused:           s.paused,
     }
 }
 
@@ -103,6 +170,83 @@ func determineSeatId(event Event, players map[string]*player) (int, error) {
 	return getRandomTrueKey(openSeats)
 }
 
+func (s *state) findPlayer(user string) (*player, error) {
+	p, ok := s.players[user]
+	if !ok {
+		return nil, errors.New("player is not at the table")
+	}
+	return p, nil
+}
+
+func (s *state) verifyOwner(user string) error {
+	if s.owner != "" && s.owner != user {
+		return errors.New("only the table owner can do that")
+	}
+	return nil
+}
+
+// buy-in limits are in big blinds, 0 means no limit
+func (s *state) setBuyIn(minBuyIn float64, maxBuyIn float64) error {
+	if minBuyIn < 0 || maxBuyIn < 0 || (maxBuyIn > 0 && maxBuyIn < minBuyIn) {
+		return errors.New("invalid buy-in limits")
+	}
+	s.minBuyIn = minBuyIn
+	s.maxBuyIn = maxBuyIn
+	s.prevState = nil
+	return nil
+}
+
+func (s *state) verifyBuyIn(user string, chips float64) error {
+	if chips <= 0 {
+		return errors.New("buy-in must be positive")
+	}
+
+	minChips := s.minBuyIn * s.bigBlind
+	maxChips := s.maxBuyIn * s.bigBlind
+	// a player coming back soon after leaving has to bring at least the stack they left with,
+	// even if that's more than the table maximum
+	if d, ok := s.departures[user]; ok && s.clock.now().Sub(d.at) < config.AppConfig.RATHOLE_WINDOW {
+		if chips < d.chips {
+			return fmt.Errorf("must buy in for at least %v after leaving recently", d.chips)
+		}
+		minChips = max(minChips, d.chips)
+		if s.maxBuyIn > 0 {
+			maxChips = max(maxChips, d.chips)
+		}
+	}
+
+	if chips < minChips {
+		return errors.New("buy-in is below the table minimum")
+	}
+	if s.maxBuyIn > 0 && chips > maxChips {
+		return errors.New("buy-in is above the table maximum")
+	}
+	return nil
+}
+
+// top-ups count chips already committed to the hand and chips waiting to be added
+func (s *state) verifyTopUp(p *player, chips float64) error {
+	if chips <= 0 {
+		return errors.New("top-up must be positive")
+	}
+	stack := p.chips + p.chipsInHand + p.pendingChips
+	if s.maxBuyIn > 0 && stack+chips > s.maxBuyIn*s.bigBlind {
+		return errors.New("top-up would exceed the table maximum")
+	}
+	return nil
+}
+
+func (s *state) recordDeparture(p *player) {
+	for user, d := range s.departures {
+		if s.clock.now().Sub(d.at) >= config.AppConfig.RATHOLE_WINDOW {
+			delete(s.departures, user)
+		}
+	}
+	if p.chips > 0 {
+		s.departures[p.user] = departure{chips: p.chips, at: s.clock.now()}
+	}
+}
+
 func (s *state) addPlayer(p *player) error {
 	if _, exists := s.players[p.user]; exists {
 		return errors.New("player already at the table")
@@ -133,6 +277,7 @@ func (s *state) addPlayer(p *player) error {
 }
 
 func (s *state) removePlayer(p *player) {
+	s.recordDeparture(p)
 	delete(s.players, p.user)
 	if s.dealer.next == s.dealer {
 		s.dealer = nil
@@ -194,15 +339,25 @@ func (s *state) removePlayersInHand(players []*player) {
 
 func (s *state) resetDeck() {
 	s.deck = nil
-	s.communityCards = nil
+	s.discards = nil
+	s.boards = nil
 }
 
 func (s *state) resetPlayers() {
+	if s.dealer == nil {
+		return
+	}
 	pointer := s.dealer
 	for {
 		pointer.nextInHand = nil
 		pointer.holeCards = nil
-		pointer.maxWin = 0
+		pointer.upCards = nil
+		pointer.shownCards = nil
+		pointer.chipsInHand = 0
+		pointer.acted = false
+		pointer.preAction = ""
+		pointer.chips += pointer.pendingChips
+		pointer.pendingChips = 0
 
 		pointer = pointer.next
 		if pointer == s.dealer {
@@ -217,12 +372,109 @@ func (s *state) resetState() {
 	s.spotlight = nil
 	s.psuedoDealer = nil
 	s.lastAggressor = nil
+	s.aggressor = nil
+	s.folded = nil
+	s.pots = nil
 	s.street = BetweenHands
 	s.currentBet = 0.0
 	s.minRaise = 0.0
+	s.bets = 0
 	s.pot = 0.0
 	s.collectedPot = 0.0
 	s.chipsInHandTotal = 0.0
+	s.runs = 0
+	s.showdownPot = 0
+	s.payouts = nil
+	s.showdownPayout = 0
+	s.bombPot = false
+}
+
+// blinds can only change between hands, the new level is held until the next hand starts
+func (s *state) setBlinds(smallBlind float64, bigBlind float64) error {
+	if smallBlind <= 0 || bigBlind < smallBlind {
+		return errors.New("invalid blinds")
+	}
+	s.nextSmallBlind = smallBlind
+	s.nextBigBlind = bigBlind
+	return nil
+}
+
+func (s *state) applyPendingBlinds() {
+	if s.nextBigBlind == 0 {
+		return
+	}
+	s.smallBlind = s.nextSmallBlind
+	s.bigBlind = s.nextBigBlind
+	s.nextSmallBlind = 0
+	s.nextBigBlind = 0
+}
+
+// a bomb pot is dealt every bombPotEvery hands, or on the next hand once everyone sitting in has voted for one
+func (s *state) isBombPotDue() bool {
+	if !s.variant.dealsBoard() {
+		return false
+	}
+	if s.bombPotEvery > 0 && s.handCount%s.bombPotEvery == 0 {
+		return true
+	}
+	if len(s.bombPotVotes) == 0 {
+		return false
+	}
+	for _, p := range s.players {
+		if !p.sittingOut && !s.bombPotVotes[p.user] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *state) voteBombPot(user string) error {
+	p, err := s.findPlayer(user)
+	if err != nil {
+		return err
+	}
+	if p.sittingOut {
+		return errors.New("only players sitting in can vote for a bomb pot")
+	}
+	s.bombPotVotes[user] = true
+	s.prevState = nil
+	return nil
+}
+
+// the ante defaults to two big blinds if the table didn't set one
+func (s *state) bombPotAnteAmount() float64 {
+	if s.bombPotAnte > 0 {
+		return s.bombPotAnte
+	}
+	return 2 * s.bigBlind
+}
+
+// everyone in the hand antes instead of posting blinds, a player who can't cover it is all in
+// and the side pot is built when the street ends
+func (s *state) postBombPotAntes() {
+	ante := s.bombPotAnteAmount()
+	pointer := s.psuedoDealer
+	for {
+		pointer.putChipsInPot(s, min(ante, pointer.chips))
+		s.record(pointer.user, "ante", pointer.chipsInPot)
+		s.currentBet = max(s.currentBet, pointer.chipsInPot)
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			break
+		}
+	}
+	s.minRaise = s.bigBlind
+}
+
+// the button ante is dead money, it goes straight into the collected pot and doesn't count toward
+// the dealer's bet on the first street
+func (s *state) postButtonAnte() {
+	ante := min(s.bigBlind, s.dealer.chips)
+	s.dealer.chips -= ante
+	s.dealer.chipsInHand += ante
+	s.pot += ante
+	s.collectedPot += ante
+	s.record(s.dealer.user, "ante", ante)
 }
 
 func (s *state) totalChips() float64 {
@@ -233,35 +485,45 @@ func (s *state) totalChips() float64 {
 	return total
 }
 
-func (s *state) checkInvariants(engineState engineState) {
+// checkInvariants returns the first rule of the game the state breaks, if any
+func (s *state) checkInvariants(engineState engineState) error {
 	// Chip conservation during a hand
 	if s.chipsInHandTotal > 0 {
 		got := s.totalChips()
 		if math.Abs(got-s.chipsInHandTotal) > 0.001 {
-			log.Fatalf("INVARIANT: chip conservation violated: expected=%.4f got=%.4f", s.chipsInHandTotal, got)
+			return fmt.Errorf("chip conservation violated: expected=%.4f got=%.4f", s.chipsInHandTotal, got)
 		}
 	}
 
-	// No negative chips or pot values
+	// No negative chips or pot values, anything within a thousandth of a chip is rounding
 	for user, p := range s.players {
-		if p.chips < 0 {
-			log.Fatalf("INVARIANT: player %s has negative chips: %.4f", user, p.chips)
+		if p.chips < -0.001 {
+			return fmt.Errorf("player %s has negative chips: %.4f", user, p.chips)
 		}
-		if p.chipsInPot < 0 {
-			log.Fatalf("INVARIANT: player %s has negative chipsInPot: %.4f", user, p.chipsInPot)
+		if p.chipsInPot < -0.001 {
+			return fmt.Errorf("player %s has negative chipsInPot: %.4f", user, p.chipsInPot)
 		}
 	}
-	if s.pot < 0 {
-		log.Fatalf("INVARIANT: pot is negative: %.4f", s.pot)
+	if s.pot < -0.001 {
+		return fmt.Errorf("pot is negative: %.4f", s.pot)
 	}
-	if s.collectedPot < 0 {
-		log.Fatalf("INVARIANT: collectedPot is negative: %.4f", s.collectedPot)
+	if s.collectedPot < -0.001 {
+		return fmt.Errorf("collectedPot is negative: %.4f", s.collectedPot)
 	}
 
-	// Community cards must be 0, 3, 4, or 5
-	n := len(s.communityCards)
-	if n != 0 && n != 3 && n != 4 && n != 5 {
-		log.Fatalf("INVARIANT: invalid community card count: %d", n)
+	// Once the pots are built they hold everything collected, until the showdown starts paying them
+	if len(s.pots) > 0 && engineState != StateShowdown && engineState != StatePauseAfterShowdown {
+		if total := s.potsTotal(); math.Abs(total-s.collectedPot) > 0.001 {
+			return fmt.Errorf("pots add up to %.4f but %.4f was collected", total, s.collectedPot)
+		}
+	}
+
+	// Community cards on every board must be 0, 3, 4, or 5, stud only shares cards when the deck runs short
+	for _, board := range s.boards {
+		n := len(board)
+		if !s.variant.stud && n != 0 && n != 3 && n != 4 && n != 5 {
+			return fmt.Errorf("invalid community card count: %d", n)
+		}
 	}
 
 	// Dealer linked list length must equal player count
@@ -271,7 +533,7 @@ func (s *state) checkInvariants(engineState engineState) {
 		for {
 			count++
 			if count > len(s.players)+1 {
-				log.Fatalf("INVARIANT: dealer linked list longer than player count (broken cycle)")
+				return fmt.Errorf("dealer linked list longer than player count (broken cycle)")
 			}
 			ptr = ptr.next
 			if ptr == s.dealer {
@@ -279,20 +541,20 @@ func (s *state) checkInvariants(engineState engineState) {
 			}
 		}
 		if count != len(s.players) {
-			log.Fatalf("INVARIANT: dealer linked list length %d != player count %d", count, len(s.players))
+			return fmt.Errorf("dealer linked list length %d != player count %d", count, len(s.players))
 		}
 	}
 
 	// Spotlight must be valid and non-all-in during game command processing
 	if engineState == StateProcessGameCommands {
 		if s.spotlight == nil {
-			log.Fatalf("INVARIANT: spotlight is nil in StateProcessGameCommands")
+			return fmt.Errorf("spotlight is nil in StateProcessGameCommands")
 		}
 		if s.spotlight.isAllIn() {
-			log.Fatalf("INVARIANT: spotlight player %s is all-in in StateProcessGameCommands", s.spotlight.user)
+			return fmt.Errorf("spotlight player %s is all-in in StateProcessGameCommands", s.spotlight.user)
 		}
 		if s.spotlight.nextInHand == nil {
-			log.Fatalf("INVARIANT: spotlight.nextInHand is nil in StateProcessGameCommands")
+			return fmt.Errorf("spotlight.nextInHand is nil in StateProcessGameCommands")
 		}
 	}
 
@@ -307,15 +569,23 @@ func (s *state) checkInvariants(engineState engineState) {
 		StatePauseAfterEndStreet,
 		StateShowdown,
 		StatePauseAfterShowdown,
-		StateDealStreet:
+		StateDealStreet,
+		StateDraw,
+		StateDiscard:
 		inHoleCardState = true
 	}
 	if inHoleCardState && s.psuedoDealer != nil {
 		ptr := s.psuedoDealer
 		for {
-			if len(ptr.holeCards) != 2 {
-				log.Fatalf("INVARIANT: player %s has %d hole cards (expected 2) in engineState %d",
-					ptr.user, len(ptr.holeCards), engineState)
+			got, expected := len(ptr.holeCards), s.expectedHoleCards(engineState)
+			if s.variant.stud {
+				got += len(ptr.upCards) + len(s.boards[0])
+			}
+			// during a pineapple discard some players have thrown their extra card away and some haven't
+			discarding := engineState == StateDiscard && got == expected-1
+			if got != expected && !discarding {
+				return fmt.Errorf("player %s has %d hole cards (expected %d) in engineState %d",
+					ptr.user, got, expected, engineState)
 			}
 			ptr = ptr.nextInHand
 			if ptr == s.psuedoDealer {
@@ -323,6 +593,18 @@ func (s *state) checkInvariants(engineState engineState) {
 			}
 		}
 	}
+	return nil
+}
+
+// expectedHoleCards depends on the game and how far into the hand it is
+func (s *state) expectedHoleCards(engineState engineState) int {
+	if s.variant.stud {
+		return s.studCardsDealt(engineState)
+	}
+	if s.hasDiscarded(engineState) {
+		return s.variant.holeCards - 1
+	}
+	return s.variant.holeCards
 }
 
 func (s *state) sitoutBustedPlayers() error {
@@ -396,6 +678,8 @@ func (s *state) collectPot() {
 	pointer := s.dealer
 	for {
 		pointer.chipsInPot = 0
+		pointer.acted = false
+		pointer.preAction = ""
 		pointer = pointer.next
 		if pointer == s.dealer {
 			break
@@ -407,12 +691,83 @@ func (s *state) isEveryoneFolded() bool {
 	return s.countPlayersInHand() == 1
 }
 
+// betting is closed when at most one player in the hand still has chips behind
+func (s *state) isBettingClosed() bool {
+	canAct := 0
+	pointer := s.psuedoDealer
+	for {
+		if !pointer.isAllIn() {
+			canAct++
+		}
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			break
+		}
+	}
+	return canAct <= 1
+}
+
+func (s *state) isRunItVoteDue() bool {
+	return s.runItTwice && s.variant.dealsBoard() && s.runs == 0 && s.countPlayersInHand() > 1 && s.isBettingClosed()
+}
+
+func (s *state) isRunItVoteComplete() bool {
+	pointer := s.psuedoDealer
+	for {
+		if pointer.runItVote == 0 {
+			return false
+		}
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			return true
+		}
+	}
+}
+
+// the board is run the fewest times anyone asked for, anyone who didn't vote counts as once
+func (s *state) resolveRunItVote() {
+	s.runs = config.AppConfig.MAX_RUNS
+	pointer := s.psuedoDealer
+	for {
+		s.runs = min(s.runs, max(pointer.runItVote, 1))
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			break
+		}
+	}
+
+	// each run gets its own copy of the cards dealt so far
+	boards := make([][]poker.Card, 0, len(s.boards)*s.runs)
+	for run := 0; run < s.runs; run++ {
+		boards = append(boards, copyBoards(s.boards)...)
+	}
+	s.boards = boards
+}
+
+func copyBoards(boards [][]poker.Card) [][]poker.Card {
+	copied := make([][]poker.Card, len(boards))
+	for i, board := range boards {
+		copied[i] = append([]poker.Card{}, board...)
+	}
+	return copied
+}
+
 func (s *state) isStreetComplete() bool {
 	return s.spotlight == s.lastAggressor
 }
 
+// streetIndex is how many betting rounds into the hand the current street is
+func (s *state) streetIndex() int {
+	for i, st := range s.variant.streets {
+		if st == s.street {
+			return i
+		}
+	}
+	return 0
+}
+
 func (s *state) isStreetRiver() bool {
-	return s.street == River
+	return s.street == s.variant.streets[len(s.variant.streets)-1]
 }
 
 func (s *state) isStreetFlop() bool {
@@ -420,13 +775,11 @@ func (s *state) isStreetFlop() bool {
 }
 
 func (s *state) goToNextStreet() {
-	switch s.street {
-	case Preflop:
-		s.street = Flop
-	case Flop:
-		s.street = Turn
-	case Turn:
-		s.street = River
+	for i, st := range s.variant.streets[:len(s.variant.streets)-1] {
+		if st == s.street {
+			s.street = s.variant.streets[i+1]
+			return
+		}
 	}
 }
 
@@ -437,6 +790,23 @@ func (s *state) rotateSpotlight() {
 	}
 }
 
+// the blinds can put a short stack all in before anyone acts, the first player who still can
+// acts first instead, returns true if nobody has a decision left and the street is already over
+func (s *state) skipAllInBlinds() bool {
+	if s.spotlight == nil || !s.spotlight.isAllIn() {
+		return false
+	}
+	start := s.spotlight
+	for s.spotlight.isAllIn() {
+		s.spotlight = s.spotlight.nextInHand
+		if s.spotlight == start {
+			return true
+		}
+	}
+	s.lastAggressor = s.spotlight
+	return s.isBettingClosed() && s.spotlight.chipsInPot >= s.currentBet
+}
+
 func (s *state) rotateDealer() error {
 	if s.dealer == nil {
 		return errors.New("dealer is nil")
@@ -491,7 +861,11 @@ func (s *state) performDealerRotation() error {
 		return err
 	}
 
-	if err := s.rotateDealer(); err != nil {
+	// stud has no button, it stays where the last flop game left it so the next one picks up
+	// from there, it only moves on if that player has sat out
+	if s.variant.stud && !s.dealer.sittingOut {
+		s.psuedoDealer = s.dealer
+	} else if err := s.rotateDealer(); err != nil {
 		return err
 	}
 
@@ -502,53 +876,63 @@ func (s *state) performDealerRotation() error {
 	return nil
 }
 
-func (s *state) payoutWinners(winners []*player) {
-	// takes in list of winner(s) and pays them out in order of maxWin asc
-	sortWinnersByMaxWin(winners)
-	for len(winners) > 0 {
-		smallestMaxWin := winners[0]
-		if smallestMaxWin.maxWin > 0 {
-			s.distributeChips(winners, smallestMaxWin.maxWin)
-		}
+// payout is one equal share of every pot, there's one per board and in hi-lo games a second
+// one per board for the low half
+type payout struct {
+	board int
+	low   bool
+}
 
-		winners = winners[1:]
+// startShowdown settles the pots, every payout then pays an equal share of each of them
+func (s *state) startShowdown() {
+	s.buildPots()
+	s.payouts = make([]payout, 0)
+	for board := range s.boards {
+		s.payouts = append(s.payouts, payout{board: board})
+		if s.variant.evaluateLow != nil {
+			s.payouts = append(s.payouts, payout{board: board, low: true})
+		}
 	}
+	s.showdownPayout = 0
+	s.showdownPot = 0
+	s.showHands()
 }
 
-// sortWinnersByMaxWin sorts the winners slice by their maxWin in ascending order
-func sortWinnersByMaxWin(winners []*player) {
-	sort.Slice(winners, func(i, j int) bool {
-		return winners[i].maxWin < winners[j].maxWin
-	})
+// nextShowdownPot moves on to the next pot, then the next payout, false once everything is paid
+func (s *state) nextShowdownPot() bool {
+	s.showdownPot++
+	if s.showdownPot < len(s.pots) {
+		return true
+	}
+	s.showdownPot = 0
+	s.showdownPayout++
+	if s.showdownPayout < len(s.payouts) {
+		return true
+	}
+	s.pots = nil
+	return false
 }
 
-// decrease the maxWin for all players after the chips have been distributed to the winners (since they're can still be payouts left)
-func decreaseMaxWin(psuedoDealer *player, amount float64, winnersSet map[*player]bool) {
-	pointer := psuedoDealer
-	for {
-		if _, exists := winnersSet[pointer]; !exists {
-			pointer.maxWin -= amount
-		}
-		pointer = pointer.nextInHand
-		if pointer == psuedoDealer {
-			break
-		}
+// before startShowdown there's only the high half of the first board
+func (s *state) currentPayout() payout {
+	if len(s.payouts) == 0 {
+		return payout{}
 	}
+	return s.payouts[s.showdownPayout]
 }
 
-// distributeChips divides the chips from the smallest maxWin among all winners.
-func (s *state) distributeChips(winners []*player, amount float64) {
-	winnersSet := make(map[*player]bool)
-	for _, winner := range winners {
-		winner.chips += amount / float64(len(winners))
-		winner.maxWin -= amount
-		log.Println(winner.user, " wins ", amount/float64(len(winners)), "with", poker.RankString(poker.Evaluate(append(winner.holeCards, s.communityCards...))))
-		winnersSet[winner] = true
+// the low half goes to the best qualifying low, if nobody eligible for a pot qualifies the
+// best high hand takes that half too
+func (s *state) payoutEvaluator(p payout) evaluator {
+	if !p.low {
+		return s.variant.evaluate
+	}
+	return func(holeCards []poker.Card, board []poker.Card) int32 {
+		if rank, ok := s.variant.evaluateLow(holeCards, board); ok {
+			return rank
+		}
+		return noLowQualifier + s.variant.evaluate(holeCards, board)
 	}
-	s.pot -= amount
-	s.collectedPot -= amount
-
-	decreaseMaxWin(s.psuedoDealer, amount, winnersSet)
 }
 
 func (s *state) printPlayers() string {
@@ -597,7 +981,8 @@ func (s *state) hasStateChanged() bool {
 	   prev.spotlight != curr.spotlight || 
 	   prev.street != curr.street || 
 	   prev.pot != curr.pot ||
-	   !CompareCardSlices(prev.communityCards, curr.communityCards) {
+	   prev.paused != curr.paused ||
+	   !compareBoards(prev.boards, curr.boards) {
 		return true
 	}
 
@@ -616,37 +1001,6 @@ func (s *state) hasStateChanged() bool {
     return false
 }
 
-// creates sidepots (maxWin) for each player in the pot
-func createSidePots(psuedoDealer *player, currentBet float64, collectedPot float64, pot float64) {
-	pointer := psuedoDealer
-	for {
-		// we need to check if maxWin is 0 to see if it's already been calculated on a previous street
-		if pointer.isAllIn() && pointer.chipsInPot <= currentBet && pointer.maxWin == 0 {
-			pointer.maxWin = createSidePot(pointer, collectedPot)
-		} else if !pointer.isAllIn() {
-			pointer.maxWin = pot
-		}
-		pointer = pointer.nextInHand
-		if pointer == psuedoDealer {
-			break
-		}
-	}
-}
-
-// add the collectedPot (pot from previous street) + chipsInPot from each player (if hero can match it)
-func createSidePot(hero *player, collectedPot float64) float64 {
-	maxWin := collectedPot
-	villian := hero
-	for {
-		maxWin += min(hero.chipsInPot, villian.chipsInPot)
-		villian = villian.next
-		if villian == hero {
-			break
-		}
-	}
-	return maxWin
-}
-
 func findDebugBestHand(seatId int32) int32 {
 	if seatId < 5 {
 		return 1
@@ -655,17 +1009,16 @@ func findDebugBestHand(seatId int32) int32 {
 	}
 }
 
-func findBestHand(psuedoDealer *player, communityCards []poker.Card) []*player {
+func findBestHand(players []*player, board []poker.Card, evaluate evaluator) []*player {
 	bestHand := int32(math.MaxInt32)
 	winners := make([]*player, 0)
 
-	pointer := psuedoDealer
-	for {
+	for _, pointer := range players {
 		var rank int32
 		if config.AppConfig.DEBUG {
 			rank = findDebugBestHand(int32(pointer.seatId))
 		} else {
-			rank = poker.Evaluate(append(pointer.holeCards, communityCards...))
+			rank = evaluate(pointer.holeCards, pointer.handBoard(board))
 		}
 
 		if rank < bestHand {
@@ -676,11 +1029,6 @@ func findBestHand(psuedoDealer *player, communityCards []poker.Card) []*player {
 		} else if rank == bestHand {
 			winners = append(winners, pointer)
 		}
-
-		pointer = pointer.nextInHand
-		if pointer == nil || pointer == psuedoDealer {
-			break
-		}
 	}
 
 	return winners
@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/chehsunliu/poker"
 	"github.com/wegman7/game-engine/config"
@@ -286,22 +289,19 @@ func TestFindBestHand(t *testing.T) {
 	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
 	p3 := createPlayer(Event{SeatId: 6, User: "user3", Chips: 100})
 
-	p1.nextInHand = p2
 	p1.holeCards = []poker.Card{
 		poker.NewCard("As"),
 		poker.NewCard("Kd"),
 	}
-	p2.nextInHand = p3
 	p2.holeCards = []poker.Card{
 		poker.NewCard("Th"),
 		poker.NewCard("9h"),
 	}
-	p3.nextInHand = p1
 	p3.holeCards = []poker.Card{
 		poker.NewCard("5s"),
 		poker.NewCard("Tc"),
 	}
-	winners := findBestHand(p1, communityCards)
+	winners := findBestHand([]*player{p1, p2, p3}, communityCards, evaluateHoldem)
 	if len(winners) != 1 || winners[0] != p1 {
 		t.Errorf("Expected p1 to win, got %v", winners)
 	}
@@ -314,7 +314,7 @@ func TestFindBestHand(t *testing.T) {
 		poker.NewCard("6h"),
 		poker.NewCard("3d"),
 	}
-	winners2 := findBestHand(p1, communityCards)
+	winners2 := findBestHand([]*player{p1, p2, p3}, communityCards, evaluateHoldem)
 	if len(winners2) != 2 || winners2[0] != p1 || winners2[1] != p2 {
 		t.Errorf("Expected p1 and p2 to split, got %v", winners2)
 	}
@@ -323,96 +323,182 @@ func TestFindBestHand(t *testing.T) {
 		poker.NewCard("As"),
 		poker.NewCard("Kd"),
 	}
-	winners3 := findBestHand(p1, communityCards)
+	winners3 := findBestHand([]*player{p1, p2, p3}, communityCards, evaluateHoldem)
 	if len(winners3) != 1 || winners3[0] != p3 {
 		t.Errorf("Expected p3 to win, got %v", winners2)
 	}
 }
 
-func TestPayoutWinners(t *testing.T) {
+func TestPayPots(t *testing.T) {
+	config.AppConfig.DEBUG = false
 	s := createState(1, 2, 30)
-	s.communityCards = []poker.Card{
+	s.boards = [][]poker.Card{{
 		poker.NewCard("Ah"),
 		poker.NewCard("Kh"),
 		poker.NewCard("3h"),
 		poker.NewCard("6c"),
 		poker.NewCard("Ac"),
-	}
+	}}
 	s.pot = 1900
+	s.collectedPot = 1900
 
 	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 0})
 	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 0})
 	p3 := createPlayer(Event{SeatId: 6, User: "user3", Chips: 0})
-	p4 := createPlayer(Event{SeatId: 7, User: "user3", Chips: 0})
+	p4 := createPlayer(Event{SeatId: 7, User: "user4", Chips: 0})
 	s.addPlayer(p1)
 	s.addPlayer(p2)
 	s.addPlayer(p3)
 	s.addPlayer(p4)
+	s.performDealerRotation()
 
-	p1.maxWin = 800
-	p2.maxWin = 1100
-	p3.maxWin = 1300
-	p4.maxWin = 1900
-
-	winners := []*player{p1, p2, p3, p4}
-
-	// we only need cards so we can log winning hand without an error, this won't affect the test because winners are already set
-	p1.holeCards = []poker.Card{
-		poker.NewCard("As"),
-		poker.NewCard("Kd"),
+	// everyone has the same hand, so every pot is split between the players in it
+	for _, p := range []*player{p1, p2, p3, p4} {
+		p.holeCards = []poker.Card{poker.NewCard("As"), poker.NewCard("Kd")}
 	}
-	p1.holeCards = []poker.Card{
-		poker.NewCard("As"),
-		poker.NewCard("Kd"),
+	s.pots = []pot{
+		{amount: 800, eligible: []*player{p1, p2, p3, p4}},
+		{amount: 300, eligible: []*player{p2, p3, p4}},
+		{amount: 200, eligible: []*player{p3, p4}},
+		{amount: 600, eligible: []*player{p4}},
 	}
-	p1.holeCards = []poker.Card{
-		poker.NewCard("As"),
-		poker.NewCard("Kd"),
+	s.payouts = []payout{{}}
+
+	e := &engine{
+		state:       s,
+		engineState: StateShowdown,
 	}
-	p1.holeCards = []poker.Card{
-		poker.NewCard("As"),
-		poker.NewCard("Kd"),
+	for i := 0; i < 20 && e.engineState != StateEndHand; i++ {
+		e.tick()
 	}
-	p1.nextInHand = p2
-	p2.nextInHand = p3
-	p3.nextInHand = p4
-	p4.nextInHand = p1
-	s.psuedoDealer = p1
-	s.payoutWinners(winners)
-
 	if p1.chips != 200 || p2.chips != 300 || p3.chips != 400 || p4.chips != 1000 {
-		t.Errorf("Expected 200, 300, 400, 100, got %v, %v, %v, %v", p1.chips, p2.chips, p3.chips, p4.chips)
+		t.Errorf("Expected 200, 300, 400, 1000, got %v, %v, %v, %v", p1.chips, p2.chips, p3.chips, p4.chips)
+	}
+	if s.pot != 0 {
+		t.Errorf("Expected the pot to be paid out, got %v", s.pot)
 	}
 }
 
-func TestCreatSidepots(t *testing.T) {
-	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 0})
-	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 0})
-	p3 := createPlayer(Event{SeatId: 6, User: "user3", Chips: 0})
+func TestBuildPots(t *testing.T) {
+	s := createState(1, 2, 30)
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 6, User: "user3", Chips: 100})
 	p4 := createPlayer(Event{SeatId: 7, User: "user4", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+	s.addPlayer(p4)
+	s.performDealerRotation()
+
+	// three all in players for 100, 200 and 300 and a big stack who covers them
+	p1.chips, p2.chips, p3.chips = 0, 0, 0
+	p1.chipsInHand = 100
+	p2.chipsInHand = 200
+	p3.chipsInHand = 300
+	p4.chipsInHand = 300
+
+	s.buildPots()
+	expected := []SerializePot{
+		{Amount: 400, Eligible: []string{"user1", "user2", "user3", "user4"}},
+		{Amount: 300, Eligible: []string{"user2", "user3", "user4"}},
+		{Amount: 200, Eligible: []string{"user3", "user4"}},
+	}
+	if got := createSerializePots(s.pots); !reflect.DeepEqual(sortedEligible(got), expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
 
-	p1.next, p1.nextInHand = p2, p2
-	p1.chipsInPot = 100
+func TestBuildPotsAfterFolds(t *testing.T) {
+	s := createState(1, 2, 30)
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 100})
+	p2 := createPlayer(Event{SeatId: 5, User: "user2", Chips: 100})
+	p3 := createPlayer(Event{SeatId: 6, User: "user3", Chips: 100})
+	s.addPlayer(p1)
+	s.addPlayer(p2)
+	s.addPlayer(p3)
+	s.performDealerRotation()
 	p1.chips = 0
 
-	p2.next, p2.nextInHand = p3, p3
-	p2.chipsInPot = 200
-	p2.chips = 0
+	// user1 is all in for 50, the other two put in 80 and then both fold, the side pot they built
+	// goes to user3 who folded last and the folded chips in the main pot stay there
+	p1.chipsInHand = 50
+	p2.chipsInHand = 80
+	p3.chipsInHand = 80
+	s.removePlayerInHand(p2)
+	s.folded = append(s.folded, p2)
+	s.removePlayerInHand(p3)
+	s.folded = append(s.folded, p3)
+
+	s.buildPots()
+	expected := []SerializePot{
+		{Amount: 150, Eligible: []string{"user1"}},
+		{Amount: 60, Eligible: []string{"user3"}},
+	}
+	if got := createSerializePots(s.pots); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func sortedEligible(pots []SerializePot) []SerializePot {
+	for _, p := range pots {
+		sort.Strings(p.Eligible)
+	}
+	return pots
+}
+
+func TestBuyInRules(t *testing.T) {
+	config.AppConfig.RATHOLE_WINDOW = time.Minute
+	s := createState(1, 2, 30)
+	clock := newFakeClock()
+	s.clock = clock
+	s.setBuyIn(20, 100)
 
-	p3.next, p3.nextInHand = p4, p4
-	p3.chipsInPot = 300
-	p3.chips = 0
+	if err := s.verifyBuyIn("user1", 0); err == nil {
+		t.Errorf("Expected zero buy-in to be rejected")
+	}
+	if err := s.verifyBuyIn("user1", 30); err == nil {
+		t.Errorf("Expected buy-in below 20bb to be rejected")
+	}
+	if err := s.verifyBuyIn("user1", 250); err == nil {
+		t.Errorf("Expected buy-in above 100bb to be rejected")
+	}
+	if err := s.verifyBuyIn("user1", 150); err != nil {
+		t.Errorf("Expected nil, got %s", err.Error())
+	}
+
+	p1 := createPlayer(Event{SeatId: 1, User: "user1", Chips: 150})
+	s.addPlayer(p1)
+	if err := p1.addChips(&Event{Chips: 60}, nil, s); err == nil {
+		t.Errorf("Expected top-up past the max buy-in to be rejected")
+	}
+	if err := p1.addChips(&Event{Chips: -10}, nil, s); err == nil {
+		t.Errorf("Expected negative top-up to be rejected")
+	}
 
-	p4.next, p4.nextInHand = p1, p1
-	p4.chipsInPot = 400
-	p4.chips = 100
+	s.street = Flop
+	p1.addChips(&Event{Chips: 40}, nil, s)
+	if p1.chips != 150 || p1.pendingChips != 40 {
+		t.Errorf("Expected top-up to wait for the hand to end, got %v, %v", p1.chips, p1.pendingChips)
+	}
+	s.resetState()
+	if p1.chips != 190 || p1.pendingChips != 0 {
+		t.Errorf("Expected 190 after the hand, got %v", p1.chips)
+	}
 
-	currentBet := 300.0
-	collectedPot := 1000.0
-	pot := 2000.0
+	// leaving with more than the max means coming back with at least that much
+	p1.chips = 300
+	s.removePlayer(p1)
+	if err := s.verifyBuyIn("user1", 200); err == nil {
+		t.Errorf("Expected ratholing player to be rejected")
+	}
+	if err := s.verifyBuyIn("user1", 300); err != nil {
+		t.Errorf("Expected nil, got %s", err.Error())
+	}
 
-	createSidePots(p1, currentBet, collectedPot, pot)
-	if p1.maxWin != 1400 || p2.maxWin != 1700 || p3.maxWin != 1900 || p4.maxWin != 2000 {
-		t.Errorf("Expected 1400, 1700, 1900, 2000, got %v, %v, %v, %v", p1.chips, p2.chips, p3.chips, p4.chips)
+	// once the window has passed the table limits apply again
+	clock.advance(time.Minute)
+	if err := s.verifyBuyIn("user1", 200); err != nil {
+		t.Errorf("Expected nil, got %s", err.Error())
 	}
-}
\ No newline at end of file
+}
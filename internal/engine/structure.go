@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// betting structures, how much a player may bet or raise
+const (
+	noLimit    = "noLimit"
+	potLimit   = "potLimit"
+	fixedLimit = "fixedLimit"
+)
+
+var structures = map[string]bool{
+	noLimit:    true,
+	potLimit:   true,
+	fixedLimit: true,
+}
+
+// a fixed limit street is capped after a bet and three raises
+const fixedLimitCap = 4
+
+func verifyStructure(structure string) error {
+	if structure != "" && !structures[structure] {
+		return fmt.Errorf("unknown betting structure: %s", structure)
+	}
+	return nil
+}
+
+// verifyBetSize checks a raise to raiseTo against the table's betting structure, a player going
+// all in for less than the minimum is always allowed
+func (s *state) verifyBetSize(p *player, raiseTo float64, allIn bool) error {
+	switch s.structure {
+	case fixedLimit:
+		if s.bets >= fixedLimitCap {
+			return errors.New("betting is capped")
+		}
+		target := s.fixedLimitRaiseTo()
+		if raiseTo != target && !(allIn && raiseTo < target) {
+			return fmt.Errorf("bet must be %v", target)
+		}
+		return nil
+	case potLimit:
+		if maxRaiseTo := s.potLimitRaiseTo(p); raiseTo > maxRaiseTo {
+			return fmt.Errorf("bet can't be more than the pot, the most is %v", maxRaiseTo)
+		}
+	}
+	if raiseTo < s.currentBet+s.minRaise && !allIn {
+		return errors.New("bet amount is less than minimum")
+	}
+	return nil
+}
+
+// the first two streets are played with the small bet (the big blind) and the rest with the big bet,
+// an incomplete bet like the stud bring in is completed to a full bet
+func (s *state) fixedLimitRaiseTo() float64 {
+	betSize := s.bigBlind
+	if s.streetIndex() >= 2 {
+		betSize = 2 * s.bigBlind
+	}
+	if s.currentBet < betSize {
+		return betSize
+	}
+	return s.currentBet + betSize
+}
+
+// a pot sized raise is a call followed by a raise of everything in the pot after the call
+func (s *state) potLimitRaiseTo(p *player) float64 {
+	call := s.currentBet - p.chipsInPot
+	return s.currentBet + s.pot + call
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"math"
+	"sort"
+
+	"github.com/chehsunliu/poker"
+)
+
+// stud games have no blinds or button, everyone antes and the worst upcard on third street is
+// forced to bring it in for the small blind, the big blind is the completion
+
+// anteAmount defaults to a fifth of the big blind when the table doesn't set an ante
+func (s *state) anteAmount() float64 {
+	if s.ante > 0 {
+		return s.ante
+	}
+	return s.bigBlind / 5
+}
+
+// antes are dead money, they go straight into the collected pot and don't count toward anyone's bet
+func (s *state) postAntes() {
+	ante := s.anteAmount()
+	pointer := s.psuedoDealer
+	for {
+		amount := min(ante, pointer.chips)
+		pointer.chips -= amount
+		pointer.chipsInHand += amount
+		s.pot += amount
+		s.collectedPot += amount
+		s.record(pointer.user, "ante", amount)
+		pointer = pointer.nextInHand
+		if pointer == s.psuedoDealer {
+			break
+		}
+	}
+}
+
+func (s *state) dealStudCards() {
+	pointer := s.dealer.nextInHand
+	for {
+		pointer.holeCards = s.deck.draw(s.variant.holeCards)
+		pointer.upCards = s.deck.draw(1)
+		if pointer == s.dealer {
+			break
+		}
+		pointer = pointer.nextInHand
+	}
+}
+
+// fourth through sixth street are dealt face up and seventh face down, if there aren't enough
+// cards left for everyone one card is dealt face up in the middle and shared by the whole table
+func (s *state) dealStudStreet() {
+	if s.deck.remaining() < s.countPlayersInHand() {
+		s.boards[0] = append(s.boards[0], s.deck.draw(1)...)
+		return
+	}
+	pointer := s.psuedoDealer.nextInHand
+	for {
+		if s.street == SeventhStreet {
+			pointer.holeCards = append(pointer.holeCards, s.deck.draw(1)...)
+		} else {
+			pointer.upCards = append(pointer.upCards, s.deck.draw(1)...)
+		}
+		if pointer == s.psuedoDealer {
+			break
+		}
+		pointer = pointer.nextInHand
+	}
+}
+
+// the bring in opens third street, the player after them acts first and the bring in acts last
+func (s *state) postBringIn() {
+	bringIn := s.findBringIn()
+	bringIn.putChipsInPot(s, min(s.smallBlind, bringIn.chips))
+	s.record(bringIn.user, "bringIn", bringIn.chipsInPot)
+	s.currentBet = bringIn.chipsInPot
+	s.minRaise = s.bigBlind - s.currentBet
+	if s.minRaise <= 0 {
+		s.minRaise = s.bigBlind
+	}
+	s.lastAggressor = bringIn.nextInHand
+	s.bets = 0
+	if !s.isBettingClosed() {
+		s.spotlight = firstToAct(bringIn.nextInHand)
+	}
+}
+
+// in stud the lowest upcard brings it in and in razz the highest, suits break ties
+func (s *state) findBringIn() *player {
+	bringIn := s.psuedoDealer
+	pointer := s.psuedoDealer.nextInHand
+	for pointer != s.psuedoDealer {
+		if s.bringInRank(pointer.upCards[0]) < s.bringInRank(bringIn.upCards[0]) {
+			bringIn = pointer
+		}
+		pointer = pointer.nextInHand
+	}
+	return bringIn
+}
+
+// bringInRank is lowest for the card that has to bring it in
+func (s *state) bringInRank(card poker.Card) int32 {
+	if s.variant.lowball {
+		return -(lowRanks([]poker.Card{card})[0]*4 + suitOrder(card))
+	}
+	return card.Rank()*4 + suitOrder(card)
+}
+
+// clubs are the lowest suit, then diamonds, hearts and spades
+func suitOrder(card poker.Card) int32 {
+	switch card.Suit() {
+	case 8:
+		return 0
+	case 4:
+		return 1
+	case 2:
+		return 2
+	}
+	return 3
+}
+
+// after third street the best hand showing acts first, ties go to the first player left of the dealer
+func (s *state) resetStudSpotlight() {
+	best := s.psuedoDealer.nextInHand
+	pointer := best.nextInHand
+	for pointer != best {
+		if s.showingRank(pointer.upCards) < s.showingRank(best.upCards) {
+			best = pointer
+		}
+		pointer = pointer.nextInHand
+	}
+
+	s.spotlight = firstToAct(best)
+	s.lastAggressor = best
+	s.minRaise = s.bigBlind
+	s.bets = 0
+}
+
+func firstToAct(p *player) *player {
+	for p.isAllIn() {
+		p = p.nextInHand
+	}
+	return p
+}
+
+// showingRank ranks the upcards where lower is better, only pairs, trips and quads count,
+// straights and flushes don't
+func (s *state) showingRank(upCards []poker.Card) int32 {
+	if s.variant.lowball {
+		return groupedRankKey(lowRanks(upCards))
+	}
+	ranks := make([]int32, len(upCards))
+	for i, card := range upCards {
+		ranks[i] = card.Rank() + 2
+	}
+	return -groupedRankKey(ranks)
+}
+
+// the categories groupedRankKey sorts hands into, with only the ranks of the cards to go on
+const (
+	noPair = iota
+	onePair
+	twoPair
+	threeOfAKind
+	fullHouse
+	fourOfAKind
+)
+
+var groupedRankNames = map[int32]string{
+	onePair:      "Pair",
+	twoPair:      "Two Pair",
+	threeOfAKind: "Three of a Kind",
+	fullHouse:    "Full House",
+	fourOfAKind:  "Four of a Kind",
+}
+
+// groupedRankKey ranks cards by how they pair up and then by rank, the biggest group and the
+// highest rank first, a larger key is the stronger high hand and the weaker low hand
+func groupedRankKey(ranks []int32) int32 {
+	counts := make(map[int32]int)
+	for _, rank := range ranks {
+		counts[rank]++
+	}
+	sorted := append([]int32{}, ranks...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if counts[sorted[i]] != counts[sorted[j]] {
+			return counts[sorted[i]] > counts[sorted[j]]
+		}
+		return sorted[i] > sorted[j]
+	})
+
+	pairs := 0
+	trips := false
+	category := int32(noPair)
+	for _, count := range counts {
+		switch count {
+		case 4:
+			category = fourOfAKind
+		case 3:
+			trips = true
+		case 2:
+			pairs++
+		}
+	}
+	switch {
+	case category == fourOfAKind:
+	case trips && pairs > 0:
+		category = fullHouse
+	case trips:
+		category = threeOfAKind
+	case pairs > 1:
+		category = twoPair
+	case pairs == 1:
+		category = onePair
+	}
+	return category<<20 + lowRankKey(sorted)
+}
+
+// razz is played for the lowest five cards out of seven, aces are low and straights and flushes
+// don't count, a pair is worse than any five different cards
+func evaluateRazz(holeCards []poker.Card, board []poker.Card) int32 {
+	cards := make([]poker.Card, 0, len(holeCards)+len(board))
+	cards = append(cards, holeCards...)
+	cards = append(cards, board...)
+
+	best := int32(math.MaxInt32)
+	for _, hand := range combinations(cards, 5) {
+		best = min(best, groupedRankKey(lowRanks(hand)))
+	}
+	return best
+}
+
+func describeRazz(rank int32) string {
+	if name, ok := groupedRankNames[rank>>20]; ok {
+		return name
+	}
+	description := ""
+	for shift := 16; shift >= 0; shift -= 4 {
+		if description != "" {
+			description += "-"
+		}
+		description += lowCardNames[(rank>>shift)&0xf]
+	}
+	return description + " low"
+}
+
+var lowCardNames = map[int32]string{
+	1: "A", 2: "2", 3: "3", 4: "4", 5: "5", 6: "6", 7: "7",
+	8: "8", 9: "9", 10: "T", 11: "J", 12: "Q", 13: "K",
+}
+
+// studCardsDealt is how many cards each player should have by the current street, including any
+// shared in the middle, in StateDealStreet the street has moved on but its card isn't dealt yet
+func (s *state) studCardsDealt(engineState engineState) int {
+	dealt := 3 + s.streetIndex()
+	if engineState == StateDealStreet {
+		dealt--
+	}
+	return dealt
+}
@@ -27,6 +27,18 @@ func CompareCardSlices(slice1, slice2 []poker.Card) bool {
 	return true
 }
 
+func compareBoards(boards1, boards2 [][]poker.Card) bool {
+	if len(boards1) != len(boards2) {
+		return false
+	}
+	for i := range boards1 {
+		if !CompareCardSlices(boards1[i], boards2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func getRandomTrueKey(m map[int]bool) (int, error) {
 	var keys []int
 	for k, v := range m {
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/chehsunliu/poker"
+)
+
+// variant describes what changes from game to game, the engine asks the table's variant
+// instead of assuming texas hold'em
+type variant struct {
+	name      string
+	holeCards int
+	boards    int
+	streets   []street
+	// the betting structure the game is played with unless the table picks another
+	structure   string
+	newDeck     func() *deck
+	evaluate    evaluator
+	evaluateLow lowEvaluator
+	// describe turns a rank from evaluate back into a hand name for the logs
+	describe func(rank int32) string
+	// the dealer posts a dead ante the size of the big blind for the whole table
+	buttonAnte bool
+	// stud deals each player their own up and down cards, everyone antes and the worst upcard brings it in
+	stud bool
+	// lowball games are won by the lowest hand, in razz the highest upcard brings it in and the lowest board acts first
+	lowball bool
+	// draw games deal everything in the hole and players swap cards between betting rounds
+	draw bool
+	// pineapple games deal one extra hole card which is thrown away once this street's betting is done
+	discardOn street
+}
+
+var (
+	flopStreets = []street{Preflop, Flop, Turn, River}
+	studStreets = []street{ThirdStreet, FourthStreet, FifthStreet, SixthStreet, SeventhStreet}
+	drawStreets = []street{Predraw, FirstDraw, SecondDraw, ThirdDraw}
+)
+
+const defaultVariant = "holdem"
+
+var variants = map[string]*variant{
+	"holdem": {
+		name:      "holdem",
+		holeCards: 2,
+		boards:    1,
+		streets:   flopStreets,
+		structure: noLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateHoldem,
+		describe:  poker.RankString,
+	},
+	// two boards are dealt every hand and each pot is split between the best hand on each board
+	"doubleBoardHoldem": {
+		name:      "doubleBoardHoldem",
+		holeCards: 2,
+		boards:    2,
+		streets:   flopStreets,
+		structure: noLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateHoldem,
+		describe:  poker.RankString,
+	},
+	// omaha 8 or better, half of every pot to the best high hand and half to the best qualifying low
+	"omahaHiLo": {
+		name:        "omahaHiLo",
+		holeCards:   4,
+		boards:      1,
+		streets:     flopStreets,
+		structure:   fixedLimit,
+		newDeck:     newStandardDeck,
+		evaluate:    evaluateOmaha,
+		describe:    poker.RankString,
+		evaluateLow: evaluateOmahaLow,
+	},
+	// short deck (6+) plays with 36 cards, a flush beats a full house and A-6-7-8-9 is a straight
+	"shortDeck": {
+		name:       "shortDeck",
+		holeCards:  2,
+		boards:     1,
+		streets:    flopStreets,
+		structure:  noLimit,
+		newDeck:    newShortDeck,
+		evaluate:   evaluateShortDeck,
+		describe:   describeShortDeck,
+		buttonAnte: true,
+	},
+	// pineapple is holdem with three hole cards, one is thrown away before the flop
+	"pineapple": {
+		name:      "pineapple",
+		holeCards: 3,
+		boards:    1,
+		streets:   flopStreets,
+		structure: noLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateHoldem,
+		describe:  poker.RankString,
+		discardOn: Preflop,
+	},
+	// crazy pineapple keeps all three hole cards until the flop betting is done
+	"crazyPineapple": {
+		name:      "crazyPineapple",
+		holeCards: 3,
+		boards:    1,
+		streets:   flopStreets,
+		structure: noLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateHoldem,
+		describe:  poker.RankString,
+		discardOn: Flop,
+	},
+	// seven card stud, two down and one up on third street, three more up and the last one down
+	"stud": {
+		name:      "stud",
+		holeCards: 2,
+		boards:    1,
+		streets:   studStreets,
+		structure: fixedLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateHoldem,
+		describe:  poker.RankString,
+		stud:      true,
+	},
+	// stud eight or better, half to the best high hand and half to the best qualifying low
+	"studHiLo": {
+		name:        "studHiLo",
+		holeCards:   2,
+		boards:      1,
+		streets:     studStreets,
+		structure:   fixedLimit,
+		newDeck:     newStandardDeck,
+		evaluate:    evaluateHoldem,
+		describe:    poker.RankString,
+		evaluateLow: evaluateStudLow,
+		stud:        true,
+	},
+	// razz is stud played for the lowest hand, aces low with no qualifier
+	"razz": {
+		name:      "razz",
+		holeCards: 2,
+		boards:    1,
+		streets:   studStreets,
+		structure: fixedLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateRazz,
+		describe:  describeRazz,
+		stud:      true,
+		lowball:   true,
+	},
+	// 2-7 triple draw, five cards and three draws to make the lowest hand, aces are high and
+	// straights and flushes count against you
+	"tripleDraw": {
+		name:      "tripleDraw",
+		holeCards: 5,
+		boards:    1,
+		streets:   drawStreets,
+		structure: fixedLimit,
+		newDeck:   newStandardDeck,
+		evaluate:  evaluateDeuceToSeven,
+		describe:  describeDeuceToSeven,
+		lowball:   true,
+		draw:      true,
+	},
+}
+
+// dealsBoard is false for games that don't share community cards, there's nothing to run twice
+// and no flop for a bomb pot to skip to
+func (v *variant) dealsBoard() bool {
+	return !v.stud && !v.draw
+}
+
+func lookupVariant(name string) (*variant, error) {
+	if name == "" {
+		name = defaultVariant
+	}
+	v, ok := variants[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown game: %s", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"github.com/chehsunliu/poker"
+)
+
+// PlayerView is what one player can see of the table, their own hole cards included
+type PlayerView struct {
+	User       string         `json:"user"`
+	Game       string         `json:"game"`
+	Structure  string         `json:"structure"`
+	Street     street         `json:"street"`
+	HoleCards  []poker.Card   `json:"holeCards"`
+	UpCards    []poker.Card   `json:"upCards"`
+	Boards     [][]poker.Card `json:"boards"`
+	Chips      float64        `json:"chips"`
+	ChipsInPot float64        `json:"chipsInPot"`
+	Pot        float64        `json:"pot"`
+	CurrentBet float64        `json:"currentBet"`
+	BigBlind   float64        `json:"bigBlind"`
+	Opponents  int            `json:"opponents"`
+	PreAction  string         `json:"preAction"`
+}
+
+// LegalActions is everything the player may do right now, bets are the amount to raise to
+type LegalActions struct {
+	Fold    bool    `json:"fold"`
+	Check   bool    `json:"check"`
+	Call    bool    `json:"call"`
+	Bet     bool    `json:"bet"`
+	MinBet  float64 `json:"minBet"`
+	MaxBet  float64 `json:"maxBet"`
+	Discard bool    `json:"discard"`
+	// MinDiscards and MaxDiscards bound how many cards a discard may throw away
+	MinDiscards int  `json:"minDiscards"`
+	MaxDiscards int  `json:"maxDiscards"`
+	RunIt       bool `json:"runIt"`
+	// Show is left out of any, nobody has to show and the hand doesn't wait for them
+	Show bool `json:"show"`
+}
+
+func (l LegalActions) any() bool {
+	return l.Fold || l.Check || l.Call || l.Bet || l.Discard || l.RunIt
+}
+
+func createPlayerView(s *state, p *player) PlayerView {
+	opponents := 0
+	if p.nextInHand != nil {
+		opponents = s.countPlayersInHand() - 1
+	}
+	return PlayerView{
+		User:       p.user,
+		Game:       s.variant.name,
+		Structure:  s.structure,
+		Street:     s.street,
+		HoleCards:  p.holeCards,
+		UpCards:    p.upCards,
+		Boards:     s.boards,
+		Chips:      p.chips,
+		ChipsInPot: p.chipsInPot,
+		Pot:        s.pot,
+		CurrentBet: s.currentBet,
+		BigBlind:   s.bigBlind,
+		Opponents:  opponents,
+		PreAction:  p.preAction,
+	}
+}
+
+func (s *state) legalActions(p *player, engineState engineState) LegalActions {
+	legal := LegalActions{}
+	switch engineState {
+	case StateProcessGameCommands:
+		if s.spotlight != p {
+			return legal
+		}
+		legal.Fold = true
+		legal.Check = p.chipsInPot == s.currentBet
+		legal.Call = p.chipsInPot < s.currentBet
+		allIn := p.chipsInPot + p.chips
+		if allIn > s.currentBet && p.canRaise(s) && !(s.structure == fixedLimit && s.bets >= fixedLimitCap) {
+			legal.Bet = true
+			legal.MinBet = min(s.currentBet+s.minRaise, allIn)
+			legal.MaxBet = allIn
+			switch s.structure {
+			case fixedLimit:
+				legal.MinBet = min(s.fixedLimitRaiseTo(), allIn)
+				legal.MaxBet = legal.MinBet
+			case potLimit:
+				legal.MaxBet = max(min(s.potLimitRaiseTo(p), allIn), legal.MinBet)
+			}
+		}
+	case StateDraw:
+		if s.spotlight == p {
+			legal.Discard = true
+			legal.MaxDiscards = len(p.holeCards)
+		}
+	case StateDiscard:
+		if p.nextInHand != nil && len(p.holeCards) == s.variant.holeCards {
+			legal.Discard = true
+			legal.MinDiscards = 1
+			legal.MaxDiscards = 1
+		}
+	case StateRunItVote:
+		legal.RunIt = p.nextInHand != nil && p.runItVote == 0
+	}
+	if isBettingOver(engineState) {
+		legal.Show = p.nextInHand != nil && len(p.shownCards) < len(p.holeCards)
+	}
+	return legal
+}
@@ -16,6 +16,19 @@ type Event struct {
     SeatId        int     `json:"seatId"`
 	User          string  `json:"user"`
 	Chips         float64 `json:"chips"`
+	Target        string  `json:"target"`
+	SmallBlind    float64 `json:"smallBlind"`
+	BigBlind      float64 `json:"bigBlind"`
+	MinBuyIn      float64 `json:"minBuyIn"`
+	MaxBuyIn      float64 `json:"maxBuyIn"`
+	Runs          int     `json:"runs"`
+	Cards         []string `json:"cards"`
+	Game          string  `json:"game"`
+	Structure     string  `json:"structure"`
+	// PreAction is checkFold, check, call or callAny, played once it's the player's turn
+	PreAction     string  `json:"preAction"`
+	// Bot seats a built in bot instead of a person on join, it names which bot
+	Bot           string  `json:"bot"`
 }
 
 func deserializeMessage(message []byte) (Event, error) {
@@ -55,7 +68,20 @@ func readLoop(conn *websocket.Conn, e *engine) bool {
 	}
 }
 
-func CreateEngineConn(roomName string, smallBlind float64, bigBlind float64) {
+func CreateEngineConn(req StartGameRequest) {
+	runEngineConn(req.RoomName, func(conn *websocket.Conn) (*engine, error) {
+		return createEngine(conn, req), nil
+	})
+}
+
+// RestoreEngineConn reconnects a table from a snapshot, seating players with the chips they had
+func RestoreEngineConn(snapshot TableSnapshot) {
+	runEngineConn(snapshot.RoomName, func(conn *websocket.Conn) (*engine, error) {
+		return restoreEngine(conn, snapshot)
+	})
+}
+
+func runEngineConn(roomName string, newEngine func(conn *websocket.Conn) (*engine, error)) {
 	token, err := getUserToken(os.Getenv("EMAIL"), os.Getenv("PASSWORD"))
 	if err != nil {
 		log.Fatal("could not retreive user token:", err)
@@ -63,7 +89,6 @@ func CreateEngineConn(roomName string, smallBlind float64, bigBlind float64) {
 
 	const maxRetries = 5
 	var e *engine
-	stopEngine := make(chan struct{})
 
 	for attempt := range maxRetries {
 		if attempt > 0 {
@@ -79,18 +104,36 @@ func CreateEngineConn(roomName string, smallBlind float64, bigBlind float64) {
 		}
 
 		if e == nil {
-			e = createEngine(conn, roomName, smallBlind, bigBlind)
-			go e.run(stopEngine)
+			e, err = newEngine(conn)
+			if err != nil {
+				log.Printf("Failed to create engine for room %s: %v", roomName, err)
+				conn.Close()
+				releaseEngine(roomName)
+				return
+			}
+			registerEngine(e)
+			go e.run()
 		} else {
+			e.mu.Lock()
 			e.conn = conn
+			e.mu.Unlock()
 		}
 
 		if clean := readLoop(conn, e); clean {
-			close(stopEngine)
+			e.stop()
+			deleteSnapshot(roomName)
+			return
+		}
+		// the admin api closes the connection itself when it force stops a room
+		if e.isStopped() {
 			return
 		}
 	}
 
 	log.Printf("Failed to maintain connection after %d attempts, stopping engine", maxRetries)
-	close(stopEngine)
-}
\ No newline at end of file
+	if e != nil {
+		e.stop()
+	} else {
+		releaseEngine(roomName)
+	}
+}